@@ -1,23 +1,59 @@
 package main
 
 import (
+	"github.com/afosto/cli/cmd/afosto/api"
+	"github.com/afosto/cli/cmd/afosto/auth"
+	"github.com/afosto/cli/cmd/afosto/config"
 	"github.com/afosto/cli/cmd/afosto/files"
+	"github.com/afosto/cli/cmd/afosto/gql"
 	"github.com/afosto/cli/cmd/afosto/template"
+	pkgauth "github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/client"
+	pkgconfig "github.com/afosto/cli/pkg/config"
 	"github.com/spf13/cobra"
 	"os"
 )
 
 var (
-	rootCmd = &cobra.Command{}
+	rootCmd = &cobra.Command{
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := cli.LoadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			client.Configure(cfg)
+			pkgauth.Configure(cfg)
+			return nil
+		},
+	}
 )
 
 func init() {
+	rootCmd.PersistentFlags().String("profile", "", "Select the profile to use (defaults to $AFOSTO_PROFILE or the active profile)")
+	rootCmd.PersistentFlags().String("token", "", "Use this access token instead of logging in (defaults to $AFOSTO_TOKEN)")
+	rootCmd.PersistentFlags().String("tenant", "", "Require the token to belong to this tenant (defaults to $AFOSTO_TENANT)")
+	rootCmd.PersistentFlags().IntSlice("callback-port", nil, "Receive the browser redirect of a login on the first free port of these")
+
+	rootCmd.PersistentFlags().String("config", "", "Select the configuration file (defaults to $AFOSTO_CONFIG or ~/.config/afosto/config.yml)")
+	for _, key := range pkgconfig.Keys {
+		rootCmd.PersistentFlags().String(key.Flag(), "", key.Description+" (defaults to $"+key.Env()+")")
+	}
+
+	rootCmd.AddCommand(auth.GetCommands()...)
+	rootCmd.AddCommand(config.GetCommands()...)
 	rootCmd.AddCommand(template.GetCommands()...)
 	rootCmd.AddCommand(files.GetCommands()...)
+	rootCmd.AddCommand(gql.GetCommands()...)
+	rootCmd.AddCommand(api.GetCommands()...)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, cancel := cli.InterruptContext()
+	defer cancel()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		cancel()
 		os.Exit(1)
 	}
 }
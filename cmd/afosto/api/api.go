@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var (
+	ErrorFieldsAndInput = errors.New("use either --field or --input to set the body")
+)
+
+func request(cmd *cobra.Command, args []string) {
+	method := strings.ToUpper(args[0])
+	path := args[1]
+
+	fields, _ := cmd.Flags().GetStringArray("field")
+	input, _ := cmd.Flags().GetString("input")
+	headerFlags, _ := cmd.Flags().GetStringArray("header")
+	paginate, _ := cmd.Flags().GetBool("paginate")
+	jq, _ := cmd.Flags().GetString("jq")
+	output, _ := cmd.Flags().GetString("output")
+	scopes, _ := cmd.Flags().GetStringSlice("scope")
+
+	header, err := parseHeaders(headerFlags)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	path, body, err := buildRequest(cmd, method, path, fields, input)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	if body != nil && header.Get("content-type") == "" {
+		header.Set("content-type", "application/json")
+	}
+
+	ac := cli.GetClient(cmd, auth.MergeScopes(auth.MergeScopes(auth.RenderScopes, auth.FileScopes), scopes))
+
+	pages := []interface{}{}
+	cursor := ""
+	for {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		b, _, err := ac.Request(cmd.Context(), method, path, reader, header)
+		if err != nil {
+			logging.Log.Fatal(err)
+		}
+
+		var page interface{}
+		if err := json.Unmarshal(b, &page); err != nil {
+			// a response that is not JSON is printed as it is
+			if len(pages) == 0 {
+				_, _ = os.Stdout.Write(b)
+				return
+			}
+			logging.Log.Fatalf("page after `%s` is not JSON: %s", cursor, err)
+		}
+		pages = append(pages, page)
+
+		next := nextCursor(page)
+		if !paginate || next == "" || next == cursor {
+			break
+		}
+		cursor = next
+		if path, err = withCursor(path, cursor); err != nil {
+			logging.Log.Fatal(err)
+		}
+	}
+
+	values, err := cli.Extract(combinePages(pages), jq)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	for _, value := range values {
+		// strings selected with --jq are printed raw, so they can be used in scripts
+		if s, ok := value.(string); ok && jq != "" {
+			fmt.Println(s)
+			continue
+		}
+		if err := cli.Print(os.Stdout, value, output); err != nil {
+			logging.Log.Fatal(err)
+		}
+	}
+}
+
+// buildRequest adds the fields to the query of the path or to the JSON body, or reads the body from the input
+func buildRequest(cmd *cobra.Command, method string, path string, fields []string, input string) (string, []byte, error) {
+	if input != "" {
+		if len(fields) > 0 {
+			return "", nil, ErrorFieldsAndInput
+		}
+		if input == "-" {
+			b, err := ioutil.ReadAll(cmd.InOrStdin())
+			return path, b, err
+		}
+		b, err := ioutil.ReadFile(input)
+		return path, b, err
+	}
+	if len(fields) == 0 {
+		return path, nil, nil
+	}
+
+	values, err := cli.ParseFields(fields)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		u, err := url.Parse(path)
+		if err != nil {
+			return "", nil, err
+		}
+		query := u.Query()
+		for key, value := range values {
+			if s, ok := value.(string); ok {
+				query.Set(key, s)
+			} else {
+				b, _ := json.Marshal(value)
+				query.Set(key, string(b))
+			}
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil, nil
+	}
+
+	b, err := json.Marshal(values)
+	return path, b, err
+}
+
+func parseHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, h := range headers {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid header `%s`, use key:value", h)
+		}
+		header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+// nextCursor returns page.after of a listing
+func nextCursor(page interface{}) string {
+	object, _ := page.(map[string]interface{})
+	meta, _ := object["page"].(map[string]interface{})
+	after, _ := meta["after"].(string)
+	return after
+}
+
+func withCursor(path string, cursor string) (string, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("page[after]", cursor)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// combinePages returns the single page as it is, or the data of all pages in one list
+func combinePages(pages []interface{}) interface{} {
+	if len(pages) == 1 {
+		return pages[0]
+	}
+	data := []interface{}{}
+	for _, page := range pages {
+		object, _ := page.(map[string]interface{})
+		if items, ok := object["data"].([]interface{}); ok {
+			data = append(data, items...)
+		}
+	}
+	return map[string]interface{}{"data": data}
+}
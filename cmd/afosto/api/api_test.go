@@ -0,0 +1,80 @@
+package api
+
+import (
+	"github.com/spf13/cobra"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCombinePages(t *testing.T) {
+	single := map[string]interface{}{"id": "p1"}
+	if combined := combinePages([]interface{}{single}); !reflect.DeepEqual(combined, single) {
+		t.Errorf("expected a single page as it is, got %v", combined)
+	}
+
+	pages := []interface{}{
+		map[string]interface{}{"data": []interface{}{"a", "b"}, "page": map[string]interface{}{"after": "c1"}},
+		map[string]interface{}{"data": []interface{}{"c"}, "page": map[string]interface{}{"after": "c2"}},
+		map[string]interface{}{"data": []interface{}{}},
+		"not a listing",
+	}
+	expected := map[string]interface{}{"data": []interface{}{"a", "b", "c"}}
+	if combined := combinePages(pages); !reflect.DeepEqual(combined, expected) {
+		t.Errorf("expected %v, got %v", expected, combined)
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	tests := map[string]interface{}{
+		"c1": map[string]interface{}{"page": map[string]interface{}{"after": "c1"}},
+		"":   map[string]interface{}{"page": map[string]interface{}{"after": nil}},
+	}
+	for expected, page := range tests {
+		if cursor := nextCursor(page); cursor != expected {
+			t.Errorf("expected `%s`, got `%s`", expected, cursor)
+		}
+	}
+	if cursor := nextCursor([]interface{}{}); cursor != "" {
+		t.Errorf("expected no cursor for a list, got `%s`", cursor)
+	}
+}
+
+func TestWithCursor(t *testing.T) {
+	path, err := withCursor("/cnt/files?page[after]=c1&page[limit]=2", "c2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(path)
+	if u.Path != "/cnt/files" || u.Query().Get("page[after]") != "c2" || u.Query().Get("page[limit]") != "2" {
+		t.Errorf("expected the cursor to be replaced, got %s", path)
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	cmd := &cobra.Command{}
+	fields := []string{"name=shirt", "stock=3"}
+
+	path, body, err := buildRequest(cmd, "GET", "/cnt/files?q=a", fields, "")
+	if err != nil || body != nil {
+		t.Fatalf("expected no body, got %s: %v", body, err)
+	}
+	u, _ := url.Parse(path)
+	if u.Query().Get("q") != "a" || u.Query().Get("name") != "shirt" || u.Query().Get("stock") != "3" {
+		t.Errorf("expected the fields in the query, got %s", path)
+	}
+
+	path, body, err = buildRequest(cmd, "POST", "/cnt/files", fields, "")
+	if err != nil || path != "/cnt/files" || string(body) != `{"name":"shirt","stock":3}` {
+		t.Errorf("expected the fields in the body, got %s %s: %v", path, body, err)
+	}
+
+	cmd.SetIn(strings.NewReader(`{"raw":true}`))
+	if _, body, err = buildRequest(cmd, "POST", "/cnt/files", nil, "-"); err != nil || string(body) != `{"raw":true}` {
+		t.Errorf("expected the body of the input, got %s: %v", body, err)
+	}
+	if _, _, err = buildRequest(cmd, "POST", "/cnt/files", fields, "-"); err != ErrorFieldsAndInput {
+		t.Errorf("expected %v, got %v", ErrorFieldsAndInput, err)
+	}
+}
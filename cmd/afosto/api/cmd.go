@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func GetCommands() []*cobra.Command {
+	apiCmd := &cobra.Command{
+		Use:   "api <method> <path>",
+		Short: "Send a request to the API",
+		Long: `Send an authenticated request to a path of the API and print the response.
+Fields set with -F are sent as a JSON object, or as query parameters for GET, HEAD and DELETE requests.`,
+		Example: `  afosto api get iam/tenants/me
+  afosto api get storage/files -F 'filter[dir][eq]=uploads' --paginate --jq '.data[].filename'
+  afosto api post storage/files/signature --input signature.json`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			request(cmd, args)
+		}}
+
+	apiCmd.Flags().StringArrayP("field", "F", nil, "Add a field as key=value, JSON values keep their type and @path reads a file")
+	apiCmd.Flags().String("input", "", "Send the contents of the file as the body, - reads stdin")
+	apiCmd.Flags().StringArrayP("header", "H", nil, "Add a header as `key:value`")
+	apiCmd.Flags().Bool("paginate", false, "Follow the page.after cursors and combine the data of all pages")
+	apiCmd.Flags().StringP("jq", "q", "", "Print the values at the path, like .data[].id")
+	apiCmd.Flags().StringP("output", "o", cli.OutputJSON, "Print the response as "+strings.Join(cli.OutputFormats, ", "))
+	apiCmd.Flags().StringSlice("scope", nil, "Request these scopes on top of the default scopes when logging in")
+
+	return []*cobra.Command{apiCmd}
+}
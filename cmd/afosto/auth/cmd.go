@@ -0,0 +1,67 @@
+package auth
+
+import "github.com/spf13/cobra"
+
+func GetCommands() []*cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage authentication",
+		Long:  `Log in to Afosto and manage the profiles used by the other commands`,
+	}
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in",
+		Long:  `Log in through the browser and store the credentials under the selected profile`,
+		Run: func(cmd *cobra.Command, args []string) {
+			login(cmd, args)
+		}}
+
+	loginCmd.Flags().StringSlice("scope", nil, "Request these scopes instead of the scopes used by all commands")
+	loginCmd.Flags().Bool("device", false, "Log in with a code from a browser on another machine, e.g. over SSH")
+
+	logoutCmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Log out",
+		Long:  `Delete the stored credentials of the selected profile`,
+		Run: func(cmd *cobra.Command, args []string) {
+			logout(cmd, args)
+		}}
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show authentication status",
+		Long:  `Show the user, tenant, scopes and expiry of the selected profile`,
+		Run: func(cmd *cobra.Command, args []string) {
+			status(cmd, args)
+		}}
+
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Print the access token",
+		Long:  `Print the access token of the selected profile, e.g. for use with curl`,
+		Run: func(cmd *cobra.Command, args []string) {
+			token(cmd, args)
+		}}
+
+	switchCmd := &cobra.Command{
+		Use:   "switch <profile>",
+		Short: "Switch profile",
+		Long:  `Make a profile the one used when no --profile is given`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			switchProfile(cmd, args)
+		}}
+
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List profiles",
+		Long:  `List the stored profiles and the tenant they belong to`,
+		Run: func(cmd *cobra.Command, args []string) {
+			listProfiles(cmd, args)
+		}}
+
+	authCmd.AddCommand(loginCmd, logoutCmd, statusCmd, tokenCmd, switchCmd, profilesCmd)
+
+	return []*cobra.Command{authCmd}
+}
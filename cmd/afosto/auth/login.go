@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"time"
+)
+
+func login(cmd *cobra.Command, _ []string) {
+	profile := auth.ResolveProfile(cli.GetProfile(cmd))
+
+	scopes, err := cmd.Flags().GetStringSlice("scope")
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	if len(scopes) == 0 {
+		scopes = auth.MergeScopes(auth.FileScopes, auth.RenderScopes)
+	} else {
+		scopes = auth.MergeScopes(auth.IdentityScopes, scopes)
+	}
+
+	cli.SetCallbackPorts(cmd)
+
+	mode := auth.LoginBrowser
+	if device, _ := cmd.Flags().GetBool("device"); device {
+		mode = auth.LoginDevice
+	}
+
+	user, err := auth.LoginProfile(profile, scopes, mode)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	logging.Log.Infof("✔ Logged in as %s (%s) at %s with profile `%s`", user.Name, user.Email, user.TenantName, profile)
+}
+
+func logout(cmd *cobra.Command, _ []string) {
+	profile := auth.ResolveProfile(cli.GetProfile(cmd))
+	if err := auth.Logout(profile); errors.Is(err, auth.ErrorUserNotFound) {
+		logging.Log.Warnf("✗ Not logged in with profile `%s`", profile)
+		return
+	} else if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	logging.Log.Infof("✔ Logged out profile `%s`", profile)
+}
+
+func status(cmd *cobra.Command, _ []string) {
+	profile := auth.ResolveProfile(cli.GetProfile(cmd))
+	user, err := auth.GetStoredUser(profile)
+	if errors.Is(err, auth.ErrorUserNotFound) {
+		logging.Log.Fatalf("✗ Not logged in with profile `%s`, run `afosto auth login`", profile)
+	} else if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	info, err := auth.DecodeToken(user.GetAccessToken())
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	expiry := "never"
+	if !info.ExpiresAt.IsZero() {
+		expiry = info.ExpiresAt.Local().Format(time.RFC1123)
+		if info.Expired() {
+			expiry += " (expired)"
+		} else {
+			expiry += fmt.Sprintf(" (in %s)", time.Until(info.ExpiresAt).Round(time.Second))
+		}
+	}
+
+	scopes := info.Scopes
+	if len(scopes) == 0 {
+		scopes = user.Scopes
+	}
+
+	fmt.Printf("Profile: %s\n", profile)
+	fmt.Printf("User:    %s (%s)\n", user.Name, user.Email)
+	fmt.Printf("Tenant:  %s (%s)\n", user.TenantName, user.TenantID)
+	fmt.Printf("Scopes:  %s\n", strings.Join(scopes, " "))
+	fmt.Printf("Expires: %s\n", expiry)
+}
+
+func token(cmd *cobra.Command, _ []string) {
+	profile := auth.ResolveProfile(cli.GetProfile(cmd))
+	user := auth.LoadProfile(profile)
+	if user == nil {
+		fmt.Fprintf(os.Stderr, "no valid credentials for profile `%s`, run `afosto auth login`\n", profile)
+		os.Exit(1)
+	}
+
+	fmt.Println(user.GetAccessToken())
+}
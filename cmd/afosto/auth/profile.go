@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"sort"
+)
+
+func switchProfile(_ *cobra.Command, args []string) {
+	if err := auth.SwitchProfile(args[0]); err != nil {
+		logging.Log.Fatalf("✗ could not switch to profile `%s`: %s", args[0], err)
+	}
+	logging.Log.Infof("✔ Switched to profile `%s`", args[0])
+}
+
+func listProfiles(_ *cobra.Command, _ []string) {
+	s := auth.GetStore()
+	profiles, err := s.Profiles()
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	names := make([]string, 0, len(profiles.Tenants))
+	for name := range profiles.Tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		marker := " "
+		if name == profiles.Current {
+			marker = "*"
+		}
+		tenant := profiles.Tenants[name]
+		if user, err := s.Load(tenant); err == nil && user.TenantName != "" {
+			tenant = fmt.Sprintf("%s (%s)", user.TenantName, user.TenantID)
+		}
+		fmt.Printf("%s %s\t%s\n", marker, name, tenant)
+	}
+}
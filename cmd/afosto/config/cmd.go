@@ -0,0 +1,41 @@
+package config
+
+import "github.com/spf13/cobra"
+
+func GetCommands() []*cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage configuration",
+		Long:  `Read and change the configuration stored in ~/.config/afosto/config.yml`,
+	}
+
+	getCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print a configuration value",
+		Long:  `Print the value of a key as used by the other commands`,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			get(cmd, args)
+		}}
+
+	setCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Change a configuration value",
+		Long:  `Store the value of a key in the configuration file, an empty value restores the default`,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			set(cmd, args)
+		}}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configuration values",
+		Long:  `List all keys with the value used by the other commands and where it comes from`,
+		Run: func(cmd *cobra.Command, args []string) {
+			list(cmd, args)
+		}}
+
+	configCmd.AddCommand(getCmd, setCmd, listCmd)
+
+	return []*cobra.Command{configCmd}
+}
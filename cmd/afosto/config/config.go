@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/config"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func get(cmd *cobra.Command, args []string) {
+	key, err := config.GetKey(args[0])
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	cfg, err := cli.LoadConfig(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	fmt.Println(key.Get(cfg))
+}
+
+func set(cmd *cobra.Command, args []string) {
+	key, err := config.GetKey(args[0])
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	path, err := cli.GetConfigPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	file, err := config.ReadFile(path)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	if err := key.Set(file, args[1]); err != nil {
+		logging.Log.Fatal(err)
+	}
+	if err := config.WriteFile(path, file); err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	logging.Log.Infof("✔ Set `%s` in %s", key.Name, path)
+}
+
+func list(cmd *cobra.Command, _ []string) {
+	path, err := cli.GetConfigPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	file, err := config.ReadFile(path)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	cfg, err := cli.LoadConfig(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	for _, name := range config.KeyNames() {
+		key, _ := config.GetKey(name)
+		source := "default"
+		if flag := cmd.Flags().Lookup(key.Flag()); flag != nil && flag.Changed {
+			source = "--" + key.Flag()
+		} else if os.Getenv(key.Env()) != "" {
+			source = "$" + key.Env()
+		} else if key.Get(file) != "" {
+			source = path
+		}
+		fmt.Printf("%s=%s\t(%s)\n", key.Name, key.Get(cfg), source)
+	}
+}
@@ -1,6 +1,10 @@
 package files
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/afosto/cli/pkg/client"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+)
 
 func GetCommands() []*cobra.Command {
 	uploadCmd := &cobra.Command{
@@ -25,6 +29,17 @@ func GetCommands() []*cobra.Command {
 
 	downloadCmd.Flags().StringP("source", "s", "", "Select the source file or directory")
 	downloadCmd.Flags().StringP("destination", "d", "", "Choose a path to download the sources file(s) into")
+	addFilterFlags(downloadCmd)
 
 	return []*cobra.Command{uploadCmd, downloadCmd}
 }
+
+// exitOnAccessDenied stops the transfer when the API rejects the credentials, as every other file would fail as well
+func exitOnAccessDenied(err error) {
+	if client.IsUnauthorized(err) {
+		logging.Log.Fatalf("✗ the API rejected the credentials, log in again with `afosto auth login`: %s", err)
+	}
+	if client.IsForbidden(err) {
+		logging.Log.Fatalf("✗ the credentials lack permission, check the scopes with `afosto auth status`: %s", err)
+	}
+}
@@ -1,39 +1,23 @@
 package files
 
 import (
+	"context"
 	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
 	"github.com/afosto/cli/pkg/client"
 	"github.com/afosto/cli/pkg/data"
 	"github.com/afosto/cli/pkg/logging"
-	"github.com/cenkalti/backoff/v4"
 	"github.com/gen2brain/dlgs"
 	"github.com/spf13/cobra"
-	"io/ioutil"
 	"net/url"
-	"os"
+	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 )
 
 func download(cmd *cobra.Command, _ []string) {
-	user := auth.GetUser()
-
-	if user == nil {
-		user = auth.LoadFromStorage()
-	}
-
-	if user == nil {
-		user = auth.GetImplicitUser([]string{
-			"openid",
-			"email",
-			"profile",
-			"cnt:files:read",
-			"cnt:files:write",
-		})
-	}
-
-	ac := client.GetClient(user.TenantID, user.GetAccessToken())
+	ctx := cmd.Context()
+	ac := cli.GetClient(cmd, auth.FileScopes)
 
 	source, err := cmd.Flags().GetString("source")
 	if err != nil {
@@ -67,65 +51,67 @@ func download(cmd *cobra.Command, _ []string) {
 
 	destination = strings.TrimRight(destination, "/")
 
+	filter, err := getFileFilter(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
 	downloadQueue := make(chan data.File, 10)
 
 	var wg sync.WaitGroup
+	results := &summary{}
 
-	go downloadHandler(downloadQueue, ac, source, destination, &wg)
+	go downloadHandler(ctx, downloadQueue, ac, source, destination, &wg, results)
 	logging.Log.Infof("✔ Started listing Directories`")
 
-	b := backoff.NewExponentialBackOff()
-	b.MaxElapsedTime = time.Second * 600
-	b.MaxInterval = time.Second * 50
-
-	var directories = []string{}
-
-	if err := backoff.RetryNotify(func() error {
-		var err error
-		directories, err = ac.ListDirectories(source)
-
-		return err
-
-	}, backoff.WithMaxRetries(b, 5), func(err error, duration time.Duration) {
-		logging.Log.WithField("retrying in", duration).Warn(err)
-	}); err != nil {
+	directories, err := ac.ListDirectoriesContext(ctx, source)
+	if ctx.Err() != nil {
+		results.report(ctx, "Downloaded")
+		return
+	} else if err != nil {
+		exitOnAccessDenied(err)
 		logging.Log.Fatal(err)
 	}
 
 	logging.Log.Infof("✔ Finished listing directories`")
 
 	for _, directory := range directories {
-		var files []data.File
-		cursor := ""
-		for {
-			files, cursor, err = ac.ListDirectory(strings.TrimLeft(directory, "/"), cursor)
-			if err != nil {
-				logging.Log.Fatal(err)
-			}
-			for _, file := range files {
-				wg.Add(1)
-				downloadQueue <- file
-			}
-			if len(files) < 25 {
-				break
-			}
+		filter.Dir = strings.TrimLeft(directory, "/")
+		files := ac.ListFiles(ctx, filter)
+		for files.Next() {
+			wg.Add(1)
+			downloadQueue <- files.File()
+		}
+		if ctx.Err() != nil {
+			break
+		} else if err := files.Err(); err != nil {
+			exitOnAccessDenied(err)
+			logging.Log.Fatal(err)
 		}
 	}
 
+	close(downloadQueue)
 	wg.Wait()
 
-	logging.Log.Infof("✔ Downloaded all files from `%s` to `%s`", source, destination)
-
+	results.report(ctx, "Downloaded")
+	if ctx.Err() == nil {
+		logging.Log.Infof("✔ Downloaded all files from `%s` to `%s`", source, destination)
+	}
 }
 
-func downloadHandler(downloadQueue <-chan data.File, ac *client.AfostoClient, source string, destination string, wg *sync.WaitGroup) {
+func downloadHandler(ctx context.Context, downloadQueue <-chan data.File, ac *client.AfostoClient, source string, destination string, wg *sync.WaitGroup, results *summary) {
 	for file := range downloadQueue {
 		go func(file data.File, source string, destination string, wg *sync.WaitGroup) {
 			defer wg.Done()
+			if ctx.Err() != nil {
+				results.cancel()
+				return
+			}
 			fileUri, err := url.Parse(file.Url)
 
 			if err != nil {
 				logging.Log.Error(err)
+				results.fail()
 				return
 			}
 			trimmedPath := source
@@ -135,20 +121,24 @@ func downloadHandler(downloadQueue <-chan data.File, ac *client.AfostoClient, so
 
 			destinationDir := destination + strings.TrimLeft(file.Dir, source)
 
-			b, err := ac.Download(fileUri)
-			if err != nil {
+			download, err := ac.DownloadFile(ctx, fileUri, filepath.Join(destinationDir, file.Filename))
+			if ctx.Err() != nil {
+				results.cancel()
+				return
+			} else if client.IsNotFound(err) {
+				logging.Log.Warnf("✗ `%s` no longer exists, skipping it", file.Filename)
+				return
+			} else if err != nil {
+				exitOnAccessDenied(err)
 				logging.Log.Error(err)
+				results.fail()
 				return
 			}
 
-			if err := os.MkdirAll(destinationDir, 0755); err != nil {
-				logging.Log.Error("✗ Destination path does not yet exist and could not create it")
+			results.succeed()
+			if download.Resumed > 0 {
+				logging.Log.Infof("✔ Downloaded `%s` on from `%s`, resumed after %d bytes", file.Filename, file.Url, download.Resumed)
 				return
-
-			}
-
-			if err := ioutil.WriteFile(destinationDir+"/"+file.Filename, b, 0664); err != nil {
-				logging.Log.Error(err)
 			}
 			logging.Log.Infof("✔ Downloaded `%s` on from `%s`", file.Filename, file.Url)
 
@@ -0,0 +1,82 @@
+package files
+
+import (
+	"fmt"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	dateLayouts = []string{time.RFC3339, "2006-01-02"}
+)
+
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("page-size", client.DefaultPageSize, "Number of files to list per request")
+	cmd.Flags().String("mime", "", "Only select files of the mime type")
+	cmd.Flags().String("label", "", "Only select files whose label contains the text")
+	cmd.Flags().String("visibility", "", "Only select `public` or `private` files")
+	cmd.Flags().String("created-after", "", "Only select files created since the date (2006-01-02 or RFC 3339)")
+	cmd.Flags().String("created-before", "", "Only select files created until the date (2006-01-02 or RFC 3339)")
+	cmd.Flags().String("updated-after", "", "Only select files updated since the date (2006-01-02 or RFC 3339)")
+	cmd.Flags().String("updated-before", "", "Only select files updated until the date (2006-01-02 or RFC 3339)")
+	cmd.Flags().StringSlice("sort", nil, "Sort by the fields, prefix a field with - to sort descending")
+}
+
+// getFileFilter returns the filter set with the flags of addFilterFlags
+func getFileFilter(cmd *cobra.Command) (client.FileFilter, error) {
+	filter := client.FileFilter{}
+	var err error
+
+	if filter.PageSize, err = cmd.Flags().GetInt("page-size"); err != nil {
+		return filter, err
+	}
+	if filter.Mime, err = cmd.Flags().GetString("mime"); err != nil {
+		return filter, err
+	}
+	if filter.Label, err = cmd.Flags().GetString("label"); err != nil {
+		return filter, err
+	}
+	if filter.Sort, err = cmd.Flags().GetStringSlice("sort"); err != nil {
+		return filter, err
+	}
+
+	visibility, err := cmd.Flags().GetString("visibility")
+	if err != nil {
+		return filter, err
+	}
+	switch visibility {
+	case "":
+	case "public", "private":
+		isPublic := visibility == "public"
+		filter.IsPublic = &isPublic
+	default:
+		return filter, fmt.Errorf("invalid visibility `%s`, use public or private", visibility)
+	}
+
+	for flag, value := range map[string]*time.Time{
+		"created-after":  &filter.CreatedAfter,
+		"created-before": &filter.CreatedBefore,
+		"updated-after":  &filter.UpdatedAfter,
+		"updated-before": &filter.UpdatedBefore,
+	} {
+		if *value, err = getDateFlag(cmd, flag); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
+
+func getDateFlag(cmd *cobra.Command, flag string) (time.Time, error) {
+	value, err := cmd.Flags().GetString(flag)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	for _, layout := range dateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date `%s` for --%s, use 2006-01-02 or RFC 3339", value, flag)
+}
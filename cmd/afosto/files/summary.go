@@ -0,0 +1,37 @@
+package files
+
+import (
+	"context"
+	"github.com/afosto/cli/pkg/logging"
+	"sync/atomic"
+)
+
+// summary counts the outcome of the files in a transfer
+type summary struct {
+	succeeded int64
+	failed    int64
+	cancelled int64
+}
+
+func (s *summary) succeed() {
+	atomic.AddInt64(&s.succeeded, 1)
+}
+
+func (s *summary) fail() {
+	atomic.AddInt64(&s.failed, 1)
+}
+
+func (s *summary) cancel() {
+	atomic.AddInt64(&s.cancelled, 1)
+}
+
+// report logs the totals, mentioning the interruption when the context was cancelled
+func (s *summary) report(ctx context.Context, action string) {
+	entry := logging.Log.WithField("failed", atomic.LoadInt64(&s.failed))
+	if ctx.Err() != nil {
+		entry.WithField("cancelled", atomic.LoadInt64(&s.cancelled)).
+			Warnf("✗ Interrupted, %s %d files", action, atomic.LoadInt64(&s.succeeded))
+		return
+	}
+	entry.Infof("✔ %s %d files", action, atomic.LoadInt64(&s.succeeded))
+}
@@ -2,6 +2,7 @@ package files
 
 import (
 	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
 	"github.com/afosto/cli/pkg/client"
 	"github.com/afosto/cli/pkg/logging"
 	"github.com/gen2brain/dlgs"
@@ -19,23 +20,10 @@ import (
 var _ io.Reader = (*os.File)(nil)
 
 func upload(cmd *cobra.Command, args []string) {
-	user := auth.GetUser()
-
-	if user == nil {
-		user = auth.LoadFromStorage()
-	}
-
-	if user == nil {
-		user = auth.GetImplicitUser([]string{
-			"openid",
-			"email",
-			"profile",
-			"cnt:files:read",
-			"cnt:files:write",
-		})
-	}
-
-	ac := client.GetClient(user.TenantID, user.GetAccessToken())
+	ctx := cmd.Context()
+	ac := cli.GetClient(cmd, auth.FileScopes, client.WithUploadProgress(func(progress client.UploadProgress) {
+		logging.Log.Infof("↑ Uploaded part %d of %d of `%s` (%d%%)", progress.Part, progress.Parts, progress.Filename, progress.Uploaded*100/progress.Size)
+	}))
 
 	source, err := cmd.Flags().GetString("source")
 	if err != nil {
@@ -71,14 +59,21 @@ func upload(cmd *cobra.Command, args []string) {
 
 	queue := make(chan string, 25)
 	uploader := sync.WaitGroup{}
+	results := &summary{}
 	matcher := regexp.MustCompile(".*(jpe?g|png|svg|css|csv|js|txt|doc|eot|json|xls|xlsx|pdf|xml|mp4|mov|zip|md)$")
 	for i := 0; i < runtime.NumCPU(); i++ {
 		go func(group *sync.WaitGroup) {
 			for path := range queue {
+				if ctx.Err() != nil {
+					results.cancel()
+					group.Done()
+					continue
+				}
+
 				relativePath, err := filepath.Rel(source, path)
 
 				if err != nil {
-					logging.Log.Errorf("✗ failed to upload  `%s`", path, err)
+					logging.Log.Errorf("✗ failed to upload `%s`: %s", path, err)
 				}
 
 				destinationPath := filepath.Dir(destination + relativePath)
@@ -86,16 +81,29 @@ func upload(cmd *cobra.Command, args []string) {
 				//replace path for windows
 				destinationPath = strings.ReplaceAll(destinationPath, "\\", "/")
 				uploadAsPrivateFile, _ := cmd.Flags().GetBool("private")
-				signature, err := ac.GetSignature(destinationPath, "upsert", uploadAsPrivateFile)
-				if err != nil {
-					logging.Log.Warnf("✗ failed to get a signature url for  `%s`", filepath.Dir(destinationPath))
+				signature, err := ac.GetSignatureContext(ctx, destinationPath, "upsert", uploadAsPrivateFile)
+				if ctx.Err() != nil {
+					results.cancel()
+					group.Done()
+					continue
+				} else if err != nil {
+					exitOnAccessDenied(err)
+					logging.Log.Warnf("✗ failed to get a signature url for `%s`: %s", destinationPath, err)
+					results.fail()
+					group.Done()
+					continue
 				}
 
-				file, err := ac.Upload(path, filepath.Base(path), signature)
-				if err != nil {
-					logging.Log.Errorf("✗ failed to upload `%s`", path)
+				file, err := ac.UploadContext(ctx, path, filepath.Base(path), signature)
+				if ctx.Err() != nil {
+					results.cancel()
+				} else if err != nil {
+					exitOnAccessDenied(err)
+					logging.Log.Errorf("✗ failed to upload `%s`: %s", path, err)
+					results.fail()
 				} else {
 					logging.Log.Infof("✔ Uploaded `%s` on url `%s`", file.Filename, file.Url)
+					results.succeed()
 				}
 				group.Done()
 			}
@@ -108,6 +116,9 @@ func upload(cmd *cobra.Command, args []string) {
 			if err != nil {
 				return err
 			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 
 			if info.IsDir() {
 				return nil
@@ -123,10 +134,11 @@ func upload(cmd *cobra.Command, args []string) {
 			return nil
 		})
 
-	if err != nil {
+	close(queue)
+	if err != nil && ctx.Err() == nil {
 		log.Fatal(err)
 	}
 
 	uploader.Wait()
-	logging.Log.Info("✔ Finished uploading all files")
+	results.report(ctx, "Uploaded")
 }
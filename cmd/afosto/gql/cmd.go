@@ -0,0 +1,77 @@
+package gql
+
+import (
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func GetCommands() []*cobra.Command {
+	gqlCmd := &cobra.Command{
+		Use:   "gql",
+		Short: "Run a GraphQL query",
+		Long: `Run a GraphQL query against the API and print the data. The query is read from --query, --file or stdin.
+Exits with a non-zero code when the API reports errors.`,
+		Example: `  afosto gql -q '{ products { id } }'
+  afosto gql -f queries/order.graphql --var id=123 -o yaml
+  cat order.graphql | afosto gql --variables vars.json --operation GetOrder`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			query(cmd, args)
+		}}
+
+	gqlCmd.Flags().StringP("query", "q", "", "The GraphQL query to run")
+	gqlCmd.Flags().StringP("file", "f", "", "Read the query from the file, - reads stdin")
+	gqlCmd.Flags().String("operation", "", "Run the named operation of a document holding several operations")
+	gqlCmd.Flags().StringArray("var", nil, "Set a variable as key=value, JSON values keep their type and @path reads a file")
+	gqlCmd.Flags().String("variables", "", "Read the variables from the JSON file, --var overrides them")
+	gqlCmd.Flags().StringP("output", "o", cli.OutputJSON, "Print the data as "+strings.Join(cli.OutputFormats, ", "))
+	gqlCmd.Flags().StringSlice("scope", nil, "Request these scopes on top of the default read scopes when logging in")
+
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Manage the GraphQL schema",
+		Long:  `Manage the local copy of the GraphQL schema used to check queries offline`,
+	}
+
+	pullCmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Download the GraphQL schema",
+		Long:  `Introspect the GraphQL schema of the API and store it in the local cache`,
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			pull(cmd, args)
+		}}
+	pullCmd.Flags().String("schema", "", "Store the schema in this file instead of the cache")
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the queries of a template",
+		Long: `Check every query referenced in afosto.config.yml against the pulled schema, without calling the API.
+Prints the unknown fields, undefined variables and other problems with their file and line.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			validate(cmd, args)
+		}}
+	validateCmd.Flags().StringP("file", "f", "", "Define path to the config file")
+	validateCmd.Flags().String("schema", "", "Read the schema from this file instead of the cache")
+
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate types for query files",
+		Long: `Generate Go types and a JSON Schema of the data for every .graphql file under the template repository,
+using the pulled schema.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			generate(cmd, args)
+		}}
+	generateCmd.Flags().String("dir", ".", "Generate the types of the query files under this directory")
+	generateCmd.Flags().String("out", "", "Write the generated files into this directory (defaults to generated within --dir)")
+	generateCmd.Flags().String("package", "", "Name of the Go package (defaults to the name of the --out directory)")
+	generateCmd.Flags().String("schema", "", "Read the schema from this file instead of the cache")
+
+	schemaCmd.AddCommand(pullCmd)
+	gqlCmd.AddCommand(schemaCmd, validateCmd, generateCmd)
+
+	return []*cobra.Command{gqlCmd}
+}
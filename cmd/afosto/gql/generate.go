@@ -0,0 +1,154 @@
+package gql
+
+import (
+	"fmt"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/afosto/cli/pkg/schema"
+	"github.com/spf13/cobra"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+var (
+	queryExtensions = map[string]bool{".graphql": true, ".gql": true}
+)
+
+func generate(cmd *cobra.Command, _ []string) {
+	path, err := getSchemaPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	s, err := schema.Load(path)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	dir, _ := cmd.Flags().GetString("dir")
+	out, _ := cmd.Flags().GetString("out")
+	pkg, _ := cmd.Flags().GetString("package")
+	if out == "" {
+		out = filepath.Join(dir, "generated")
+	}
+	if pkg, err = packageName(pkg, out); err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	files, err := findQueryFiles(dir, out)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	if err := os.MkdirAll(out, 0755); err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	generator := schema.NewGoGenerator(pkg)
+	problems := 0
+	for _, file := range files {
+		operations, found, err := schema.LoadOperations(s, file)
+		if err != nil {
+			logging.Log.Fatal(err)
+		}
+		for _, problem := range found {
+			fmt.Println(problem.String())
+		}
+		if len(found) > 0 {
+			problems += len(found)
+			continue
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			rel = filepath.Base(file)
+		}
+		name := outputName(rel)
+
+		source, err := generator.Generate(filepath.ToSlash(rel), operations)
+		if err != nil {
+			logging.Log.Fatal(err)
+		}
+		// the extension of the query stays in the name, so names like linux.graphql never become build constraints
+		if err := ioutil.WriteFile(filepath.Join(out, name+".go"), source, 0644); err != nil {
+			logging.Log.Fatal(err)
+		}
+
+		for _, op := range operations {
+			jsonSchema, err := op.JSONSchema()
+			if err != nil {
+				logging.Log.Fatal(err)
+			}
+			schemaName := name
+			if len(operations) > 1 {
+				schemaName += "." + op.Name
+			}
+			if err := ioutil.WriteFile(filepath.Join(out, schemaName+".schema.json"), append(jsonSchema, '\n'), 0644); err != nil {
+				logging.Log.Fatal(err)
+			}
+		}
+		logging.Log.Debugf("✔ Generated the types of `%s`", rel)
+	}
+
+	if problems > 0 {
+		logging.Log.Errorf("✗ Found %d problems, the types of those queries were not generated", problems)
+		os.Exit(1)
+	}
+	logging.Log.Infof("✔ Generated the types of %d query files in %s", len(files), out)
+}
+
+// packageName returns the package, or derives one from the name of the output directory, like myqueries for
+// ./my-queries and generated for --out . within a directory named generated
+func packageName(pkg string, out string) (string, error) {
+	if pkg != "" {
+		if !token.IsIdentifier(pkg) {
+			return "", fmt.Errorf("invalid package name `%s`, use letters, digits and underscores", pkg)
+		}
+		return pkg, nil
+	}
+
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return "", err
+	}
+	pkg = strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return -1
+	}, filepath.Base(abs)))
+	if !token.IsIdentifier(pkg) {
+		return "", fmt.Errorf("cannot name the package after `%s`, set one with --package", abs)
+	}
+	return pkg, nil
+}
+
+// findQueryFiles returns the query files within the dir, skipping the output, hidden and dependency directories
+func findQueryFiles(dir string, out string) ([]string, error) {
+	files := []string{}
+	absOut, _ := filepath.Abs(out)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			abs, _ := filepath.Abs(path)
+			name := info.Name()
+			if path != dir && (abs == absOut || strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if queryExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// outputName flattens the relative path of a query file into a file name, like queries_order.graphql
+func outputName(rel string) string {
+	return strings.ToLower(strings.ReplaceAll(filepath.ToSlash(rel), "/", "_"))
+}
@@ -0,0 +1,46 @@
+package gql
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackageName(t *testing.T) {
+	dir := t.TempDir()
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	if err := os.Mkdir(filepath.Join(dir, "Shop-Queries"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "Shop-Queries")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"generated":               "generated",
+		"./my-queries":            "myqueries",
+		"out/types.v2":            "typesv2",
+		".":                       "shopqueries",
+		"gen/":                    "gen",
+		filepath.Join(dir, "ä_b"): "ä_b",
+	}
+	for out, expected := range tests {
+		if pkg, err := packageName("", out); err != nil || pkg != expected {
+			t.Errorf("%s: expected %s, got %s: %v", out, expected, pkg, err)
+		}
+	}
+
+	for _, out := range []string{"2021", "---", "/", "func"} {
+		if pkg, err := packageName("", out); err == nil {
+			t.Errorf("%s: expected an error, got %s", out, pkg)
+		}
+	}
+
+	if pkg, err := packageName("queries", "."); err != nil || pkg != "queries" {
+		t.Errorf("expected the package to be kept, got %s: %v", pkg, err)
+	}
+	if _, err := packageName("my-queries", "generated"); err == nil {
+		t.Error("expected an invalid package to be rejected")
+	}
+}
@@ -0,0 +1,106 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+)
+
+var (
+	ErrorNoQuery = errors.New("no query given, use --query, --file or pipe it into stdin")
+)
+
+func query(cmd *cobra.Command, _ []string) {
+	document, err := readDocument(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	variables, err := readVariables(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	operation, _ := cmd.Flags().GetString("operation")
+	output, _ := cmd.Flags().GetString("output")
+	scopes, _ := cmd.Flags().GetStringSlice("scope")
+
+	q, err := client.NewQuery(document, operation, variables)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	ac := cli.GetClient(cmd, auth.MergeScopes(auth.RenderScopes, scopes))
+	result, err := ac.QueryOperationContext(cmd.Context(), q)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	if result.Data != nil {
+		if err := cli.Print(os.Stdout, result.Data, output); err != nil {
+			logging.Log.Fatal(err)
+		}
+	}
+
+	// errors go to stderr, so the data can be piped into other tools
+	if len(result.Errors) > 0 {
+		for _, queryError := range result.Errors {
+			fmt.Fprintf(os.Stderr, "✗ %s\n", queryError.Error())
+		}
+		os.Exit(1)
+	}
+}
+
+// readDocument returns the query of --query, --file or stdin, in that order
+func readDocument(cmd *cobra.Command) (string, error) {
+	if document, _ := cmd.Flags().GetString("query"); document != "" {
+		return document, nil
+	}
+
+	file, _ := cmd.Flags().GetString("file")
+	if file != "" && file != "-" {
+		b, err := ioutil.ReadFile(file)
+		return string(b), err
+	}
+
+	in := cmd.InOrStdin()
+	if f, ok := in.(*os.File); ok && file == "" {
+		// a terminal on stdin means nothing was piped, so reading it would only wait for the user
+		if info, err := f.Stat(); err != nil || info.Mode()&os.ModeCharDevice != 0 {
+			return "", ErrorNoQuery
+		}
+	}
+	b, err := ioutil.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	if len(b) == 0 {
+		return "", ErrorNoQuery
+	}
+	return string(b), nil
+}
+
+// readVariables returns the variables of the --variables file, overridden by those set with --var
+func readVariables(cmd *cobra.Command) (map[string]interface{}, error) {
+	variables := map[string]interface{}{}
+	if path, _ := cmd.Flags().GetString("variables"); path != "" {
+		var err error
+		if variables, err = cli.ReadJSONFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, _ := cmd.Flags().GetStringArray("var")
+	values, err := cli.ParseFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		variables[key] = value
+	}
+	return variables, nil
+}
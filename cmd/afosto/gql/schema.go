@@ -0,0 +1,97 @@
+package gql
+
+import (
+	"fmt"
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/cli"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/afosto/cli/pkg/render"
+	"github.com/afosto/cli/pkg/schema"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+)
+
+func pull(cmd *cobra.Command, _ []string) {
+	path, err := getSchemaPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	ac := cli.GetClient(cmd, auth.RenderScopes)
+	sdl, err := schema.Pull(cmd.Context(), ac)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	if err := schema.Save(path, sdl); err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	logging.Log.Infof("✔ Stored the schema in %s", path)
+}
+
+func validate(cmd *cobra.Command, _ []string) {
+	path, err := getSchemaPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	s, err := schema.Load(path)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	configPath, err := getTemplateConfigPath(cmd)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	files, err := render.QueryFiles(configPath)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+
+	problems := 0
+	for _, file := range files {
+		found, err := schema.ValidateFile(s, file.Resolved)
+		if os.IsNotExist(err) {
+			fmt.Printf("%s: query file `%s` of %s does not exist\n", configPath, file.Path, file.Routes[0])
+			problems++
+			continue
+		} else if err != nil {
+			logging.Log.Fatal(err)
+		}
+		for _, problem := range found {
+			fmt.Println(problem.String())
+		}
+		problems += len(found)
+	}
+
+	if problems > 0 {
+		logging.Log.Errorf("✗ Found %d problems in %d queries", problems, len(files))
+		os.Exit(1)
+	}
+	logging.Log.Infof("✔ Validated %d queries", len(files))
+}
+
+// getSchemaPath returns the path of --schema, or the cached schema of the configured API
+func getSchemaPath(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("schema"); path != "" {
+		return path, nil
+	}
+	cfg, err := cli.LoadConfig(cmd)
+	if err != nil {
+		return "", err
+	}
+	return schema.CachePath(cfg.ApiURL)
+}
+
+// getTemplateConfigPath returns the path of --file, or the afosto.config.yml in the working directory
+func getTemplateConfigPath(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("file"); path != "" {
+		return path, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cwd, render.AfostoConfigFile), nil
+}
@@ -28,31 +28,8 @@ func GetCommands() []*cobra.Command {
 }
 
 func Render(cmd *cobra.Command, args []string) {
-	user := auth.GetUser()
+	ac := cli.GetClient(cmd, auth.RenderScopes)
 
-	if user == nil {
-		user = auth.LoadFromStorage()
-	}
-
-	if user == nil {
-		user = auth.GetImplicitUser([]string{
-			"openid",
-			"email",
-			"profile",
-			"cnt:index:read",
-			"iam:users:read",
-			"iam:roles:read",
-			"iam:tenants:read",
-			"lcs:locations:read",
-			"lcs:handling:read",
-			"lcs:shipments:read",
-			"odr:orders:read",
-			"odr:coupons:read",
-			"odr:invoices:read",
-			"rel:contacts:read",
-			"rel:identity:read",
-		})
-	}
 	port, err := cmd.Flags().GetInt("port")
 	if err != nil {
 		logging.Log.Fatal("invalid port number given")
@@ -70,7 +47,7 @@ func Render(cmd *cobra.Command, args []string) {
 		file = fmt.Sprintf("%s/%s", cwd, render.AfostoConfigFile)
 	}
 
-	server, err := render.GetDevelopmentServer("localhost", port, file, user)
+	server, err := render.GetDevelopmentServer("localhost", port, file, ac)
 	if err != nil {
 		logging.Log.Fatal(err)
 	}
@@ -82,6 +59,7 @@ func Render(cmd *cobra.Command, args []string) {
 	if err != nil {
 		logging.Log.Fatal("could not call browser")
 	}
-	cli.CloseHandler()
+	cli.CloseHandler(cmd.Context())
+	server.Stop()
 
 }
@@ -14,10 +14,12 @@ require (
 	github.com/pkg/browser v0.0.0-20210115035449-ce105d075bb4
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.1.1
+	github.com/vektah/gqlparser/v2 v2.5.1
 	gopkg.in/yaml.v2 v2.2.8
 )
 
 require (
+	github.com/agnivade/levenshtein v1.0.1 // indirect
 	github.com/cockroachdb/apd v1.1.0 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
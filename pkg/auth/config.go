@@ -0,0 +1,12 @@
+package auth
+
+import "github.com/afosto/cli/pkg/config"
+
+var (
+	settings = config.Default()
+)
+
+// Configure points the login flows and token verification at the endpoints of the configuration
+func Configure(cfg *config.Config) {
+	settings = cfg
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+var (
+	// sleep waits between polls of the token endpoint
+	sleep = time.Sleep
+	// authClient calls the endpoints of the authorization server, bounded so a stalled server cannot hang a login
+	authClient = &http.Client{Timeout: time.Second * 30}
+)
+
+var (
+	ErrorDeviceAccessDenied = errors.New("the login was denied")
+	ErrorDeviceCodeExpired  = errors.New("the login code expired before it was used")
+)
+
+type deviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	RefreshToken     string `json:"refresh_token"`
+	Scope            string `json:"scope"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// deviceLogin runs the device authorization flow, where the user approves the login from a browser on any machine
+func deviceLogin(permissions []string) (*data.User, error) {
+	code, err := requestDeviceCode(permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Log.Infof("Open %s in a browser and enter the code %s", code.VerificationURI, code.UserCode)
+	if code.VerificationURIComplete != "" {
+		logging.Log.Infof("Or open %s", code.VerificationURIComplete)
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for code.ExpiresIn <= 0 || time.Now().Before(deadline) {
+		sleep(interval)
+
+		token, err := postTokenForm(url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {code.DeviceCode},
+			"client_id":   {settings.ClientID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch token.Error {
+		case "":
+			u := &data.User{}
+			if err := loadTokenResponse(u, token, permissions); err != nil {
+				return nil, err
+			}
+			if err := loadTenant(u); err != nil {
+				return nil, err
+			}
+			logging.Log.Debugf("✔ Authenticated as %s (%s) at %s (%s)", u.Name, u.Email, u.TenantName, u.TenantID)
+			return u, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "access_denied":
+			return nil, ErrorDeviceAccessDenied
+		case "expired_token":
+			return nil, ErrorDeviceCodeExpired
+		default:
+			return nil, fmt.Errorf("login failed: %s %s", token.Error, token.ErrorDescription)
+		}
+	}
+
+	return nil, ErrorDeviceCodeExpired
+}
+
+func requestDeviceCode(permissions []string) (*deviceCode, error) {
+	res, err := authClient.PostForm(settings.DeviceAuthorizationURL, url.Values{
+		"client_id": {settings.ClientID},
+		"scope":     {strings.Join(MergeScopes(permissions, []string{OfflineScope}), " ")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not start device login: %s", res.Status)
+	}
+
+	code := &deviceCode{}
+	if err := json.Unmarshal(b, code); err != nil {
+		return nil, err
+	}
+	if code.VerificationURI == "" {
+		// some servers use the name from the draft specification
+		aux := struct {
+			VerificationURL string `json:"verification_url"`
+		}{}
+		_ = json.Unmarshal(b, &aux)
+		code.VerificationURI = aux.VerificationURL
+	}
+
+	return code, nil
+}
+
+// postTokenForm calls the token endpoint, OAuth errors are returned within the response
+func postTokenForm(values url.Values) (*tokenResponse, error) {
+	res, err := authClient.PostForm(settings.TokenURL, values)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &tokenResponse{}
+	if err := json.Unmarshal(b, token); err != nil {
+		return nil, fmt.Errorf("unexpected response from token endpoint: %s", res.Status)
+	}
+	if res.StatusCode != http.StatusOK && token.Error == "" {
+		return nil, fmt.Errorf("unexpected response from token endpoint: %s", res.Status)
+	}
+
+	return token, nil
+}
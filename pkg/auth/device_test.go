@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// pollDevice answers the polls of the device code with the errors, in order, and then issues tokens
+func pollDevice(t *testing.T, ts *tokenServer, errs ...string) *[]time.Duration {
+	waits := &[]time.Duration{}
+	sleep = func(d time.Duration) {
+		*waits = append(*waits, d)
+	}
+	t.Cleanup(func() {
+		sleep = time.Sleep
+	})
+
+	ts.device = func(form url.Values) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"device_code":      "device",
+			"user_code":        "ABCD-EFGH",
+			"verification_url": ts.URL + "/activate",
+			"expires_in":       600,
+			"interval":         1,
+		}
+	}
+	ts.token = func(form url.Values) (int, interface{}) {
+		if form.Get("grant_type") != deviceCodeGrantType || form.Get("device_code") != "device" {
+			t.Errorf("unexpected poll %v", form)
+		}
+		if len(errs) > 0 {
+			e := errs[0]
+			errs = errs[1:]
+			return http.StatusBadRequest, map[string]string{"error": e}
+		}
+		idToken := ts.claims("tenant", time.Hour)
+		idToken["name"] = "Jane"
+		idToken["aud"] = settings.ClientID
+		return http.StatusOK, map[string]interface{}{
+			"access_token": ts.sign(t, ts.claims("tenant", time.Hour)),
+			"id_token":     ts.sign(t, idToken),
+		}
+	}
+	return waits
+}
+
+func TestDeviceLogin(t *testing.T) {
+	ts := newTokenServer(t)
+	waits := pollDevice(t, ts, "authorization_pending", "slow_down", "authorization_pending")
+
+	u, err := deviceLogin([]string{"openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.TenantID != "tenant" || u.TenantName != "Shop tenant" || u.Name != "Jane" {
+		t.Errorf("unexpected user %+v", u)
+	}
+
+	expected := []time.Duration{time.Second, time.Second, 6 * time.Second, 6 * time.Second}
+	if len(*waits) != len(expected) {
+		t.Fatalf("expected %d polls, got %v", len(expected), *waits)
+	}
+	for i, wait := range expected {
+		if (*waits)[i] != wait {
+			t.Errorf("expected poll %d to wait %s, got %s", i+1, wait, (*waits)[i])
+		}
+	}
+}
+
+func TestDeviceLoginFails(t *testing.T) {
+	tests := map[string]error{
+		"access_denied": ErrorDeviceAccessDenied,
+		"expired_token": ErrorDeviceCodeExpired,
+	}
+	for oauthError, expected := range tests {
+		t.Run(oauthError, func(t *testing.T) {
+			ts := newTokenServer(t)
+			pollDevice(t, ts, "authorization_pending", oauthError)
+
+			if _, err := deviceLogin(nil); !errors.Is(err, expected) {
+				t.Errorf("expected %v, got %v", expected, err)
+			}
+		})
+	}
+}
+
+func TestDeviceLoginStalled(t *testing.T) {
+	ts := newTokenServer(t)
+	pollDevice(t, ts)
+	release := make(chan bool)
+	defer close(release)
+	ts.token = func(form url.Values) (int, interface{}) {
+		<-release
+		return http.StatusOK, nil
+	}
+	defaultTimeout := authClient.Timeout
+	authClient.Timeout = time.Millisecond * 100
+	defer func() {
+		authClient.Timeout = defaultTimeout
+	}()
+
+	if _, err := deviceLogin(nil); !os.IsTimeout(err) {
+		t.Errorf("expected the poll to time out, got %v", err)
+	}
+}
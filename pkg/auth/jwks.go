@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits refetching the key set when tokens are signed with unknown keys
+	jwksMinRefresh = time.Minute
+)
+
+var (
+	ErrorJWKSUnavailable   = errors.New("could not fetch the signing keys")
+	ErrorUnknownSigningKey = errors.New("token is signed with an unknown key")
+	ErrorTokenSignature    = errors.New("token signature is invalid")
+	ErrorTokenIssuer       = errors.New("token was not issued by afosto")
+	ErrorTokenAudience     = errors.New("token was not issued for this cli")
+	ErrorTokenExpired      = errors.New("token expired")
+)
+
+var (
+	signingMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+	keys           = &keySet{}
+	jwksClient     = &http.Client{Timeout: time.Second * 10}
+)
+
+// keySet caches the public keys of the issuer, by key ID
+type keySet struct {
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verifyToken checks the signature, issuer, expiry and, when requireAudience is set or the claim is present,
+// the audience of the token and returns its claims
+func verifyToken(token string, requireAudience bool) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{ValidMethods: signingMethods}
+	if _, err := parser.ParseWithClaims(token, claims, keys.keyfunc); err != nil {
+		var validationError *jwt.ValidationError
+		if !errors.As(err, &validationError) {
+			return nil, err
+		}
+		switch {
+		case validationError.Inner != nil && validationError.Errors&jwt.ValidationErrorUnverifiable != 0:
+			return nil, validationError.Inner
+		case validationError.Errors&jwt.ValidationErrorMalformed != 0:
+			return nil, ErrorInvalidToken
+		case validationError.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return nil, ErrorTokenSignature
+		case validationError.Errors&jwt.ValidationErrorExpired != 0:
+			return nil, ErrorTokenExpired
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrorInvalidToken, err)
+		}
+	}
+
+	if !claims.VerifyIssuer(settings.Issuer, true) {
+		return nil, ErrorTokenIssuer
+	}
+	if !verifyAudience(claims, settings.ClientID, requireAudience) {
+		return nil, ErrorTokenAudience
+	}
+
+	return claims, nil
+}
+
+// verifyAudience accepts both the single string and the list form of the aud claim
+func verifyAudience(claims jwt.MapClaims, audience string, required bool) bool {
+	switch aud := claims["aud"].(type) {
+	case nil:
+		return !required
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (ks *keySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if ks.keys == nil || time.Since(ks.fetchedAt) > jwksTTL {
+		if err := ks.fetch(); err != nil {
+			return nil, err
+		}
+	}
+	if key := ks.lookup(kid); key != nil {
+		return key, nil
+	}
+
+	// the issuer may have rotated its keys since they were fetched
+	if time.Since(ks.fetchedAt) > jwksMinRefresh {
+		if err := ks.fetch(); err != nil {
+			return nil, err
+		}
+		if key := ks.lookup(kid); key != nil {
+			return key, nil
+		}
+	}
+
+	return nil, ErrorUnknownSigningKey
+}
+
+func (ks *keySet) lookup(kid string) interface{} {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key
+		}
+	}
+	return ks.keys[kid]
+}
+
+func (ks *keySet) fetch() error {
+	res, err := jwksClient.Get(settings.JWKSURL)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorJWKSUnavailable, err)
+	}
+	defer res.Body.Close()
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrorJWKSUnavailable, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrorJWKSUnavailable, res.Status)
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return fmt.Errorf("%w: %s", ErrorJWKSUnavailable, err)
+	}
+
+	ks.keys = map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			ks.keys[jwk.Kid] = key
+		}
+	}
+	ks.fetchedAt = time.Now()
+
+	return nil
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
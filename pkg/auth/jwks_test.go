@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	ts := newTokenServer(t)
+	other := newKey(t)
+
+	withAudience := ts.claims("tenant", time.Hour)
+	withAudience["aud"] = []interface{}{"other", settings.ClientID}
+	wrongIssuer := ts.claims("tenant", time.Hour)
+	wrongIssuer["iss"] = "https://example.com"
+	wrongAudience := ts.claims("tenant", time.Hour)
+	wrongAudience["aud"] = "other"
+
+	tests := []struct {
+		name            string
+		token           string
+		requireAudience bool
+		err             error
+	}{
+		{name: "valid", token: ts.sign(t, ts.claims("tenant", time.Hour))},
+		{name: "audience", token: ts.sign(t, withAudience), requireAudience: true},
+		{name: "missing audience", token: ts.sign(t, ts.claims("tenant", time.Hour)), requireAudience: true, err: ErrorTokenAudience},
+		{name: "wrong audience", token: ts.sign(t, wrongAudience), err: ErrorTokenAudience},
+		{name: "wrong issuer", token: ts.sign(t, wrongIssuer), err: ErrorTokenIssuer},
+		{name: "expired", token: ts.sign(t, ts.claims("tenant", -time.Minute)), err: ErrorTokenExpired},
+		{name: "bad signature", token: signWith(t, ts.claims("tenant", time.Hour), "k1", other), err: ErrorTokenSignature},
+		{name: "malformed", token: "not.a.token", err: ErrorInvalidToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := verifyToken(test.token, test.requireAudience)
+			if test.err == nil && err != nil {
+				t.Fatalf("expected the token to verify, got %v", err)
+			}
+			if test.err == nil && claims["tenant"] != "tenant" {
+				t.Errorf("expected the claims of the token, got %v", claims)
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+	if requests := atomic.LoadInt32(&ts.jwksRequests); requests != 1 {
+		t.Errorf("expected the keys to be fetched once, got %d", requests)
+	}
+}
+
+func TestVerifyTokenKeyRotation(t *testing.T) {
+	ts := newTokenServer(t)
+	if _, err := verifyToken(ts.sign(t, ts.claims("tenant", time.Hour)), false); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newKey(t)
+	ts.publish(map[string]*rsa.PrivateKey{"k2": rotated})
+	token := signWith(t, ts.claims("tenant", time.Hour), "k2", rotated)
+
+	// the keys were fetched too recently to fetch them again
+	if _, err := verifyToken(token, false); !errors.Is(err, ErrorUnknownSigningKey) {
+		t.Fatalf("expected an unknown key, got %v", err)
+	}
+	if requests := atomic.LoadInt32(&ts.jwksRequests); requests != 1 {
+		t.Fatalf("expected no refetch within %s, got %d requests", jwksMinRefresh, requests)
+	}
+
+	keys.mu.Lock()
+	keys.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	keys.mu.Unlock()
+	if _, err := verifyToken(token, false); err != nil {
+		t.Fatalf("expected the rotated key to be fetched, got %v", err)
+	}
+	if requests := atomic.LoadInt32(&ts.jwksRequests); requests != 2 {
+		t.Errorf("expected the keys to be fetched again, got %d requests", requests)
+	}
+
+	// a key that is not published at all stays unknown
+	keys.mu.Lock()
+	keys.fetchedAt = time.Now().Add(-jwksMinRefresh - time.Second)
+	keys.mu.Unlock()
+	if _, err := verifyToken(signWith(t, ts.claims("tenant", time.Hour), "k3", rotated), false); !errors.Is(err, ErrorUnknownSigningKey) {
+		t.Errorf("expected an unknown key, got %v", err)
+	}
+}
+
+func TestVerifyTokenJWKSUnavailable(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.jwksStatus = http.StatusInternalServerError
+
+	if _, err := verifyToken(ts.sign(t, ts.claims("tenant", time.Hour)), false); !errors.Is(err, ErrorJWKSUnavailable) {
+		t.Errorf("expected the keys to be unavailable, got %v", err)
+	}
+}
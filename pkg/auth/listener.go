@@ -2,136 +2,201 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/afosto/cli/pkg/client"
 	"github.com/afosto/cli/pkg/data"
 	"github.com/afosto/cli/pkg/logging"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/browser"
-	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"sync"
+	"time"
 )
 
 const (
-	LocalAuthServer = "localhost:8888"
+	// fallbackPorts is the number of ports after the port of the redirect URL tried when it is in use
+	fallbackPorts = 4
+)
+
+var (
+	// CallbackPorts overrides the ports the listener tries, in order, to receive the redirect on
+	CallbackPorts []int
+	// LoginTimeout bounds how long the listener waits for the browser to redirect back
+	LoginTimeout = 5 * time.Minute
+)
+
+var (
+	ErrorLoginTimeout  = errors.New("timed out waiting for the login to complete in the browser")
+	ErrorStateMismatch = errors.New("login state does not match")
 )
 
 type loginResource struct {
-	wg     *sync.WaitGroup
-	server *http.Server
-	user   *data.User
+	server      *http.Server
+	redirectURL string
+	state       string
+	verifier    string
+	scopes      []string
+	once        sync.Once
+	result      chan loginResult
 }
 
-var user *data.User
-
-func GetUser() *data.User {
-	return user
+type loginResult struct {
+	user *data.User
+	err  error
 }
 
-func LoadFromStorage() *data.User {
-	if data2, err := ioutil.ReadFile("user.json"); err == nil {
-		loadedUser := data.User{}
-		if err := json.Unmarshal(data2, &loadedUser); err == nil {
-			claims := jwt.StandardClaims{}
-			parser := jwt.Parser{}
-			parser.ParseUnverified(loadedUser.GetAccessToken(), &claims)
-			if err := claims.Valid(); err == nil {
-				return &loadedUser
-			}
-		}
+// browserLogin opens the browser on the authorization page and exchanges the code it redirects back with for tokens
+func browserLogin(permissions []string) (*data.User, error) {
+	if !CanOpenBrowser() {
+		return nil, ErrorInteractiveLoginUnavailable
 	}
-	return nil
-}
 
-func GetImplicitUser(permissions []string) *data.User {
-	err := browser.OpenURL(client.GetAuthorizationURL(permissions))
+	verifier, challenge, err := newCodeVerifier()
 	if err != nil {
-		logging.Log.Fatal("could not call browser")
+		return nil, err
+	}
+	state, err := randomString()
+	if err != nil {
+		return nil, err
 	}
 
-	resource := getLoginResource()
-	go resource.startListener()
-	resource.await()
-
-	user = resource.user
+	scopes := MergeScopes(permissions, []string{OfflineScope})
+	resource, err := startLoginListener(verifier, state, scopes)
+	if err != nil {
+		return nil, err
+	}
+	defer resource.shutdown()
 
-	if userData, err := json.Marshal(user); err == nil {
-		_ = ioutil.WriteFile("user.json", userData, 0644)
+	err = browser.OpenURL(client.GetAuthorizationURL(scopes, resource.redirectURL, challenge, state))
+	if err != nil {
+		return nil, ErrorInteractiveLoginUnavailable
 	}
-	return user
 
+	return resource.await(LoginTimeout)
 }
 
-func getLoginResource() *loginResource {
-	ll := &loginResource{
-		user: &data.User{},
+// startLoginListener listens for the redirect on the first free callback port, on its own mux so logins can repeat
+func startLoginListener(verifier string, state string, scopes []string) (*loginResource, error) {
+	redirect, err := url.Parse(settings.RedirectURL)
+	if err != nil {
+		return nil, err
 	}
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	ll.wg = &wg
-	return ll
-}
 
-func (ll *loginResource) startListener() {
-	http.HandleFunc("/return", ll.loadUserData)
-	ll.server = &http.Server{Addr: LocalAuthServer}
-	go ll.server.ListenAndServe()
-	logging.Log.Debug("Connecting to Afosto/IO")
+	var listener net.Listener
+	for _, port := range callbackPorts(redirect) {
+		address := net.JoinHostPort(redirect.Hostname(), strconv.Itoa(port))
+		if listener, err = net.Listen("tcp", address); err == nil {
+			redirect.Host = address
+			break
+		}
+		logging.Log.Debugf("could not listen on %s: %s", address, err)
+	}
+	if listener == nil {
+		return nil, fmt.Errorf("could not start the login listener: %w", err)
+	}
 
-}
+	ll := &loginResource{
+		redirectURL: redirect.String(),
+		state:       state,
+		verifier:    verifier,
+		scopes:      scopes,
+		result:      make(chan loginResult, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirect.Path, ll.loadUserData)
+	ll.server = &http.Server{Handler: mux}
 
-func (ll *loginResource) await() {
-	ll.wg.Wait()
-	logging.Log.Debugf("✔ Authenticated as %s (%s) at %s (%s)",
-		ll.user.Name, ll.user.Email, ll.user.TenantName, ll.user.TenantID)
+	go func() {
+		if err := ll.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ll.finish(nil, err)
+		}
+	}()
+	logging.Log.Debug("Connecting to Afosto/IO")
 
-	_ = ll.server.Shutdown(context.Background())
+	return ll, nil
 }
 
-//Load the userdata onto the login resource
-func (ll *loginResource) loadUserData(rw http.ResponseWriter, request *http.Request) {
-	defer ll.wg.Done()
-	_, err := rw.Write([]byte("Success - you can close this window"))
+func callbackPorts(redirect *url.URL) []int {
+	if len(CallbackPorts) > 0 {
+		return CallbackPorts
+	}
+	port, err := strconv.Atoi(redirect.Port())
 	if err != nil {
-		logging.Log.Fatal(err)
+		port = 80
+	}
+	ports := []int{}
+	for i := 0; i <= fallbackPorts; i++ {
+		ports = append(ports, port+i)
 	}
+	return ports
+}
 
-	if f, ok := rw.(http.Flusher); ok {
-		f.Flush()
+func (ll *loginResource) await(timeout time.Duration) (*data.User, error) {
+	select {
+	case result := <-ll.result:
+		if result.err != nil {
+			return nil, result.err
+		}
+		logging.Log.Debugf("✔ Authenticated as %s (%s) at %s (%s)",
+			result.user.Name, result.user.Email, result.user.TenantName, result.user.TenantID)
+		return result.user, nil
+	case <-time.After(timeout):
+		return nil, ErrorLoginTimeout
 	}
+}
 
-	ll.user.SetAccessToken(request.URL.Query()["access_token"][0])
-	idTokenString := request.URL.Query()["id_token"][0]
-	accessTokenClaims := jwt.MapClaims{}
-	_, _ = jwt.ParseWithClaims(ll.user.GetAccessToken(), accessTokenClaims, func(token *jwt.Token) (interface{}, error) {
-		return token, nil
-	})
-	idTokenClaims := jwt.MapClaims{}
-	_, _ = jwt.ParseWithClaims(idTokenString, idTokenClaims, func(token *jwt.Token) (interface{}, error) {
-		return token, nil
+func (ll *loginResource) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_ = ll.server.Shutdown(ctx)
+}
+
+// finish hands over the outcome of the first completed redirect
+func (ll *loginResource) finish(u *data.User, err error) {
+	ll.once.Do(func() {
+		ll.result <- loginResult{user: u, err: err}
 	})
+}
 
-	for key, val := range idTokenClaims {
-		if key == "name" {
-			ll.user.Name = val.(string)
-		} else if key == "email" {
-			ll.user.Email = val.(string)
-		}
+// Load the userdata onto the login resource
+func (ll *loginResource) loadUserData(rw http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	// a redirect that was not started by this login is ignored, so it cannot be used to inject credentials
+	if query.Get("state") != ll.state {
+		logging.Log.Warn(ErrorStateMismatch)
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = rw.Write([]byte("Failed - " + ErrorStateMismatch.Error()))
+		return
 	}
 
-	for key, val := range accessTokenClaims {
-		if key == "tenant" {
-			ll.user.TenantID = val.(string)
-		} else if key == "sub" {
-			ll.user.ID = val.(string)
-		}
+	if oauthError := query.Get("error"); oauthError != "" {
+		err := fmt.Errorf("login failed: %s %s", oauthError, query.Get("error_description"))
+		rw.WriteHeader(http.StatusUnauthorized)
+		_, _ = rw.Write([]byte("Failed - " + err.Error()))
+		ll.finish(nil, err)
+		return
 	}
 
-	tenant, err := client.GetClient(ll.user.TenantID, ll.user.GetAccessToken()).GetTenant()
+	u := &data.User{}
+	token, err := exchangeCode(query.Get("code"), ll.redirectURL, ll.verifier)
+	if err == nil {
+		err = loadTokenResponse(u, token, ll.scopes)
+	}
+	if err == nil {
+		err = loadTenant(u)
+	}
 	if err != nil {
-		logging.Log.Fatal("could not fetch tenant")
+		rw.WriteHeader(http.StatusInternalServerError)
+		_, _ = rw.Write([]byte("Failed - " + err.Error()))
+		ll.finish(nil, err)
+		return
 	}
 
-	ll.user.TenantName = tenant.Name
+	_, _ = rw.Write([]byte("Success - you can close this window"))
+	ll.finish(u, nil)
 }
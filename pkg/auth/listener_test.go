@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func useCallbackPorts(t *testing.T, ports ...int) {
+	CallbackPorts = ports
+	t.Cleanup(func() {
+		CallbackPorts = nil
+	})
+}
+
+func TestLoginListenerFallsBackToFreePort(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	free := freePort(t)
+	useCallbackPorts(t, busy.Addr().(*net.TCPAddr).Port, free)
+
+	resource, err := startLoginListener("verifier", "state", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resource.shutdown()
+
+	if !strings.Contains(resource.redirectURL, ":"+strconv.Itoa(free)+"/") {
+		t.Errorf("expected the redirect on port %d, got %s", free, resource.redirectURL)
+	}
+}
+
+func TestLoginListenerIgnoresForeignState(t *testing.T) {
+	useCallbackPorts(t, freePort(t))
+	resource, err := startLoginListener("verifier", "state", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resource.shutdown()
+
+	res, err := http.Get(resource.redirectURL + "?state=other&code=code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a redirect with another state to be rejected, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(resource.redirectURL + "?state=state&error=access_denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if _, err := resource.await(time.Second); err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Errorf("expected the login to fail with the error of the redirect, got %v", err)
+	}
+}
+
+func TestLoginListenerTimeout(t *testing.T) {
+	useCallbackPorts(t, freePort(t))
+	resource, err := startLoginListener("verifier", "state", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resource.shutdown()
+
+	if _, err := resource.await(time.Millisecond * 10); err != ErrorLoginTimeout {
+		t.Errorf("expected %v, got %v", ErrorLoginTimeout, err)
+	}
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"errors"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultProfile = "default"
+)
+
+// LoginMode selects how the user authenticates
+type LoginMode int
+
+const (
+	// LoginBrowser opens a browser on this machine and receives the redirect locally
+	LoginBrowser LoginMode = iota
+	// LoginDevice prints a code the user enters in a browser on any machine
+	LoginDevice
+)
+
+var (
+	ErrorProfileNotFound = errors.New("profile not found")
+)
+
+var (
+	usersMu sync.Mutex
+	users   = map[string]*data.User{}
+	warned  = map[string]bool{}
+)
+
+// Profiles maps named profiles onto the tenant whose credentials they use
+type Profiles struct {
+	Current string            `json:"current"`
+	Tenants map[string]string `json:"tenants"`
+}
+
+// ResolveProfile returns the given profile name, falling back to the active profile
+func ResolveProfile(name string) string {
+	if name != "" {
+		return name
+	}
+	if profiles, err := GetStore().Profiles(); err == nil && profiles.Current != "" {
+		return profiles.Current
+	}
+	return DefaultProfile
+}
+
+// GetProfileUser returns the user for the profile, logging in through the browser when nothing valid is stored.
+// When the stored user lacks some of the permissions, it logs in again for the permissions it had and the missing ones,
+// unless the server denied them before.
+func GetProfileUser(profile string, permissions []string) (*data.User, error) {
+	profile = ResolveProfile(profile)
+
+	usersMu.Lock()
+	u, ok := users[profile]
+	usersMu.Unlock()
+
+	if !ok {
+		if u = LoadProfile(profile); u != nil {
+			usersMu.Lock()
+			users[profile] = u
+			usersMu.Unlock()
+		}
+	}
+
+	if u == nil {
+		return LoginProfile(profile, permissions, LoginBrowser)
+	}
+
+	missing := MissingScopes(u.Scopes, permissions)
+	if ungranted := MissingScopes(u.DeniedScopes, missing); len(ungranted) > 0 {
+		logging.Log.Infof("Logging in again to grant the missing scopes: %s", strings.Join(ungranted, " "))
+		return LoginProfile(profile, MergeScopes(u.Scopes, permissions), LoginBrowser)
+	}
+
+	if len(missing) > 0 {
+		usersMu.Lock()
+		if !warned[profile] {
+			logging.Log.Warnf("✗ the scopes were denied before, run `auth login` to request them again: %s", strings.Join(missing, " "))
+			warned[profile] = true
+		}
+		usersMu.Unlock()
+	}
+
+	return u, nil
+}
+
+// LoadProfile returns the stored and unexpired user of the profile, or nil
+func LoadProfile(profile string) *data.User {
+	loadedUser, err := GetStoredUser(profile)
+	if err != nil {
+		if !errors.Is(err, ErrorUserNotFound) {
+			logging.Log.Warn(err)
+		}
+		return nil
+	}
+
+	if _, err := verifyToken(loadedUser.GetAccessToken(), false); errors.Is(err, ErrorTokenExpired) {
+		if err := Refresh(loadedUser); err != nil {
+			logging.Log.Debug(err)
+			return nil
+		}
+	} else if err != nil {
+		logging.Log.Warnf("✗ discarding stored credentials: %s", err)
+		return nil
+	}
+	return loadedUser
+}
+
+// GetStoredUser returns the stored user of the profile, whether its token expired or not
+func GetStoredUser(profile string) (*data.User, error) {
+	profile = ResolveProfile(profile)
+	s := GetStore()
+	migrateLegacyUser(s)
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, ok := profiles.Tenants[profile]
+	if !ok {
+		return nil, ErrorUserNotFound
+	}
+
+	return s.Load(tenantID)
+}
+
+// Logout deletes the stored credentials of the profile
+func Logout(profile string) error {
+	profile = ResolveProfile(profile)
+	u, err := GetStoredUser(profile)
+	if err != nil {
+		return err
+	}
+
+	usersMu.Lock()
+	delete(users, profile)
+	usersMu.Unlock()
+
+	return GetStore().Delete(u.TenantID)
+}
+
+// LoginProfile authenticates the user and stores it under the profile
+func LoginProfile(profile string, permissions []string, mode LoginMode) (*data.User, error) {
+	profile = ResolveProfile(profile)
+	var u *data.User
+	var err error
+	if mode == LoginDevice {
+		u, err = deviceLogin(permissions)
+	} else {
+		u, err = browserLogin(permissions)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.DeniedScopes = MissingScopes(u.Scopes, permissions)
+	if len(u.DeniedScopes) > 0 {
+		logging.Log.Warnf("✗ the server did not grant the scopes: %s", strings.Join(u.DeniedScopes, " "))
+	}
+
+	usersMu.Lock()
+	users[profile] = u
+	warned[profile] = len(u.DeniedScopes) > 0
+	usersMu.Unlock()
+
+	s := GetStore()
+	if err := s.Save(u); err != nil {
+		logging.Log.Warnf("✗ could not store credentials: %s", err)
+		return u, nil
+	}
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		logging.Log.Warnf("✗ could not store profile: %s", err)
+		return u, nil
+	}
+	profiles.Tenants[profile] = u.TenantID
+	if profiles.Current == "" {
+		profiles.Current = profile
+	}
+	if err := s.SaveProfiles(profiles); err != nil {
+		logging.Log.Warnf("✗ could not store profile: %s", err)
+	}
+
+	return u, nil
+}
+
+// SwitchProfile makes the profile the one used when no profile is given
+func SwitchProfile(profile string) error {
+	s := GetStore()
+	profiles, err := s.Profiles()
+	if err != nil {
+		return err
+	}
+	if _, ok := profiles.Tenants[profile]; !ok {
+		return ErrorProfileNotFound
+	}
+	profiles.Current = profile
+	return s.SaveProfiles(profiles)
+}
+
+// GetUser returns the user of the active profile when it was loaded before
+func GetUser() *data.User {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	return users[ResolveProfile("")]
+}
+
+// LoadFromStorage returns the stored user of the active profile
+func LoadFromStorage() *data.User {
+	return LoadProfile("")
+}
+
+// GetImplicitUser logs in through the browser for the active profile
+func GetImplicitUser(permissions []string) *data.User {
+	u, err := LoginProfile("", permissions, LoginBrowser)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	return u
+}
@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"github.com/afosto/cli/pkg/data"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useTempStore stores credentials in a temporary file for the duration of the test
+func useTempStore(t *testing.T) Store {
+	t.Helper()
+	s := NewFileStore(filepath.Join(t.TempDir(), CredentialsFile))
+	SetStore(s)
+	t.Cleanup(func() {
+		SetStore(nil)
+	})
+	return s
+}
+
+func TestProfiles(t *testing.T) {
+	s := useTempStore(t)
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		if err := s.Save(&data.User{TenantID: tenantID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveProfiles(&Profiles{Current: "a", Tenants: map[string]string{"a": "tenant-a", "b": "tenant-b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if profile := ResolveProfile(""); profile != "a" {
+		t.Errorf("expected the active profile, got %s", profile)
+	}
+	if profile := ResolveProfile("b"); profile != "b" {
+		t.Errorf("expected the given profile, got %s", profile)
+	}
+	for profile, tenantID := range map[string]string{"a": "tenant-a", "b": "tenant-b"} {
+		if u, err := GetStoredUser(profile); err != nil || u.TenantID != tenantID {
+			t.Errorf("expected profile %s to use %s, got %+v %v", profile, tenantID, u, err)
+		}
+	}
+	if _, err := GetStoredUser("c"); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected no user for an unknown profile, got %v", err)
+	}
+
+	if err := SwitchProfile("c"); !errors.Is(err, ErrorProfileNotFound) {
+		t.Errorf("expected switching to an unknown profile to fail, got %v", err)
+	}
+	if err := SwitchProfile("b"); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := GetStoredUser(""); err != nil || u.TenantID != "tenant-b" {
+		t.Errorf("expected the user of the switched profile, got %+v %v", u, err)
+	}
+}
+
+func TestDeleteRemovesProfiles(t *testing.T) {
+	s := useTempStore(t)
+	if err := s.Save(&data.User{TenantID: "tenant-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveProfiles(&Profiles{Current: "a", Tenants: map[string]string{"a": "tenant-a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("tenant-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles.Tenants) != 0 || profiles.Current != "" {
+		t.Errorf("expected the profile of the deleted tenant to be removed, got %+v", profiles)
+	}
+}
+
+func TestDefaultProfileWithoutMapping(t *testing.T) {
+	s := useTempStore(t)
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		if err := s.Save(&data.User{TenantID: tenantID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveProfiles(&Profiles{Current: "a", Tenants: map[string]string{"a": "tenant-a", "b": "tenant-b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GetStoredUser(DefaultProfile); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected no user for the unmapped default profile, got %v", err)
+	}
+	if err := Logout(DefaultProfile); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected logging out the unmapped default profile to fail, got %v", err)
+	}
+	if _, err := s.Load(""); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected no user for an empty tenant, got %v", err)
+	}
+}
+
+func TestCredentialsBeforeProfiles(t *testing.T) {
+	s := NewFileStore(filepath.Join(t.TempDir(), CredentialsFile))
+	SetStore(s)
+	defer SetStore(nil)
+	legacy := `{"current": "tenant-b", "users": {"tenant-a": {"tenant_id": "tenant-a"}, "tenant-b": {"tenant_id": "tenant-b"}}}`
+	if err := ioutil.WriteFile(s.(*fileStore).path, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, err := GetStoredUser(DefaultProfile); err != nil || u.TenantID != "tenant-b" {
+		t.Fatalf("expected the default profile to use tenant-b, got %+v %v", u, err)
+	}
+
+	// saving another tenant no longer moves the default profile
+	if err := s.Save(&data.User{TenantID: "tenant-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := GetStoredUser(DefaultProfile); err != nil || u.TenantID != "tenant-b" {
+		t.Errorf("expected the default profile to keep using tenant-b, got %+v %v", u, err)
+	}
+}
+
+func TestMigrateLegacyUserToDefaultProfile(t *testing.T) {
+	s := useTempStore(t)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := s.Save(&data.User{TenantID: "tenant-b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(legacyUserFile, []byte(`{"tenant_id": "tenant-a", "token": "x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, err := GetStoredUser(DefaultProfile); err != nil || u.TenantID != "tenant-a" {
+		t.Errorf("expected the default profile to use the migrated user, got %+v %v", u, err)
+	}
+}
+
+func TestDeniedScopesDoNotLogInAgain(t *testing.T) {
+	ts := newTokenServer(t)
+	pollDevice(t, ts)
+
+	u, err := LoginProfile("shop", []string{"cnt:files:write"}, LoginDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(u.DeniedScopes, []string{"cnt:files:write"}) {
+		t.Fatalf("expected the denied scopes to be recorded, got %v", u.DeniedScopes)
+	}
+
+	usersMu.Lock()
+	delete(users, "shop")
+	usersMu.Unlock()
+	ts.device = nil
+	stored, err := GetProfileUser("shop", []string{"cnt:files:read", "cnt:files:write"})
+	if err != nil || stored.TenantID != "tenant" {
+		t.Fatalf("expected the stored user, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshMargin refreshes tokens this long before they expire, so they don't expire while in flight
+	refreshMargin = time.Minute
+)
+
+var (
+	ErrorNoRefreshToken = errors.New("no refresh token available, log in again")
+)
+
+type userTokenSource struct {
+	mu   sync.Mutex
+	user *data.User
+}
+
+// TokenSource returns a token source for the user that refreshes its tokens when they expire
+func TokenSource(u *data.User) client.TokenSource {
+	return &userTokenSource{user: u}
+}
+
+func (ts *userTokenSource) Token() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	token := ts.user.GetAccessToken()
+	if info, err := DecodeToken(token); err == nil && !info.ExpiresAt.IsZero() &&
+		time.Now().Add(refreshMargin).After(info.ExpiresAt) && ts.user.GetRefreshToken() != "" {
+		if err := Refresh(ts.user); err != nil {
+			return "", err
+		}
+	}
+	return ts.user.GetAccessToken(), nil
+}
+
+func (ts *userTokenSource) Refresh(expired string) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	// another request refreshed the token in the meantime
+	if token := ts.user.GetAccessToken(); token != expired {
+		return token, nil
+	}
+	if err := Refresh(ts.user); err != nil {
+		return "", err
+	}
+	return ts.user.GetAccessToken(), nil
+}
+
+// Refresh exchanges the refresh token of the user for new tokens and stores them
+func Refresh(u *data.User) error {
+	if u.GetRefreshToken() == "" {
+		return ErrorNoRefreshToken
+	}
+
+	token, err := postTokenForm(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {u.GetRefreshToken()},
+		"client_id":     {settings.ClientID},
+	})
+	if err != nil {
+		return err
+	}
+	if token.Error != "" {
+		return fmt.Errorf("could not refresh token: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	refreshed := *u
+	if err := loadTokenResponse(&refreshed, token, u.Scopes); err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		refreshed.SetRefreshToken(u.GetRefreshToken())
+	}
+	if token.IDToken == "" {
+		refreshed.Name, refreshed.Email = u.Name, u.Email
+	}
+	*u = refreshed
+
+	if err := GetStore().Save(u); err != nil {
+		logging.Log.Warnf("✗ could not store refreshed credentials: %s", err)
+	}
+	logging.Log.Debug("✔ Refreshed access token")
+
+	return nil
+}
+
+// exchangeCode redeems the authorization code received by the local listener
+func exchangeCode(code string, redirectURL string, verifier string) (*tokenResponse, error) {
+	if code == "" {
+		return nil, errors.New("no authorization code received")
+	}
+	token, err := postTokenForm(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {settings.ClientID},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("login failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	return token, nil
+}
+
+// newCodeVerifier returns a PKCE code verifier and its S256 challenge
+func newCodeVerifier() (string, string, error) {
+	verifier, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/afosto/cli/pkg/data"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCodeExchangeSendsVerifier(t *testing.T) {
+	ts := newTokenServer(t)
+	verifier, challenge, err := newCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.token = func(form url.Values) (int, interface{}) {
+		sum := sha256.Sum256([]byte(form.Get("code_verifier")))
+		if form.Get("grant_type") != "authorization_code" || form.Get("code") != "code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			return http.StatusBadRequest, map[string]string{"error": "invalid_grant"}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"access_token":  ts.sign(t, ts.claims("tenant", time.Hour)),
+			"refresh_token": "refresh",
+		}
+	}
+
+	resource := &loginResource{state: "state", verifier: verifier, result: make(chan loginResult, 1)}
+	resource.loadUserData(httptest.NewRecorder(), httptest.NewRequest("GET", "/return?code=code&state=state", nil))
+	result := <-resource.result
+	if u := result.user; result.err != nil || u.TenantID != "tenant" || u.TenantName != "Shop tenant" || u.GetRefreshToken() != "refresh" {
+		t.Errorf("unexpected user %+v: %v", u, result.err)
+	}
+
+	if _, err := exchangeCode("code", "", "other"); err == nil {
+		t.Error("expected a verifier that does not match the challenge to be rejected")
+	}
+}
+
+func TestTokenSourceRefresh(t *testing.T) {
+	ts := newTokenServer(t)
+	refreshed := ts.sign(t, ts.claims("tenant", time.Hour))
+	ts.token = func(form url.Values) (int, interface{}) {
+		if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "refresh" {
+			return http.StatusBadRequest, map[string]string{"error": "invalid_grant"}
+		}
+		return http.StatusOK, map[string]interface{}{"access_token": refreshed}
+	}
+
+	u := &data.User{TenantID: "tenant", Name: "Jane"}
+	u.SetAccessToken(ts.sign(t, ts.claims("tenant", -time.Minute)))
+	u.SetRefreshToken("refresh")
+
+	token, err := TokenSource(u).Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != refreshed || u.GetRefreshToken() != "refresh" || u.Name != "Jane" {
+		t.Errorf("expected the token to be refreshed, keeping the refresh token and name, got %+v", u)
+	}
+	if stored, err := GetStore().Load("tenant"); err != nil || stored.GetAccessToken() != refreshed {
+		t.Errorf("expected the refreshed token to be stored, got %v", err)
+	}
+
+	// a token rejected after another request refreshed it is not refreshed again
+	if token, err := TokenSource(u).Refresh("expired"); err != nil || token != refreshed {
+		t.Errorf("expected the current token, got %s %v", token, err)
+	}
+}
+
+func TestRefreshWithoutRefreshToken(t *testing.T) {
+	u := &data.User{TenantID: "tenant"}
+	if err := Refresh(u); err != ErrorNoRefreshToken {
+		t.Errorf("expected %v, got %v", ErrorNoRefreshToken, err)
+	}
+}
+
+func TestRefreshKeepsActiveProfile(t *testing.T) {
+	ts := newTokenServer(t)
+	refreshed := ts.sign(t, ts.claims("tenant-b", time.Hour))
+	ts.token = func(form url.Values) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{"access_token": refreshed}
+	}
+
+	s := GetStore()
+	if err := s.SaveProfiles(&Profiles{Current: "a", Tenants: map[string]string{"a": "tenant-a", "b": "tenant-b"}}); err != nil {
+		t.Fatal(err)
+	}
+	u := &data.User{TenantID: "tenant-b"}
+	u.SetRefreshToken("refresh")
+	if err := Refresh(u); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := s.Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profiles.Current != "a" || profiles.Tenants["a"] != "tenant-a" || profiles.Tenants["b"] != "tenant-b" {
+		t.Errorf("expected the profiles to be unchanged, got %+v", profiles)
+	}
+	if stored, err := s.Load("tenant-b"); err != nil || stored.GetAccessToken() != refreshed {
+		t.Errorf("expected the refreshed token to be stored, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+const (
+	// OfflineScope is requested on every login, so a refresh token is issued
+	OfflineScope = "offline_access"
+)
+
+var (
+	IdentityScopes = []string{
+		"openid",
+		"email",
+		"profile",
+	}
+
+	FileScopes = []string{
+		"openid",
+		"email",
+		"profile",
+		"cnt:files:read",
+		"cnt:files:write",
+	}
+
+	RenderScopes = []string{
+		"openid",
+		"email",
+		"profile",
+		"cnt:index:read",
+		"iam:users:read",
+		"iam:roles:read",
+		"iam:tenants:read",
+		"lcs:locations:read",
+		"lcs:handling:read",
+		"lcs:shipments:read",
+		"odr:orders:read",
+		"odr:coupons:read",
+		"odr:invoices:read",
+		"rel:contacts:read",
+		"rel:identity:read",
+	}
+)
+
+// MissingScopes returns the required scopes that were not granted
+func MissingScopes(granted []string, required []string) []string {
+	has := map[string]bool{}
+	for _, scope := range granted {
+		has[scope] = true
+	}
+	missing := []string{}
+	for _, scope := range required {
+		if !has[scope] {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// MergeScopes returns the unique scopes of all given sets, in order of appearance
+func MergeScopes(sets ...[]string) []string {
+	seen := map[string]bool{}
+	merged := []string{}
+	for _, set := range sets {
+		for _, scope := range set {
+			if !seen[scope] {
+				seen[scope] = true
+				merged = append(merged, scope)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/afosto/cli/pkg/config"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenServer stands in for the authorization server and the tenant endpoint of the API
+type tokenServer struct {
+	*httptest.Server
+	mu sync.Mutex
+	// keys are the published signing keys, by key ID
+	keys         map[string]*rsa.PrivateKey
+	jwksStatus   int
+	jwksRequests int32
+	// token answers the token endpoint with a status and a JSON body
+	token func(form url.Values) (int, interface{})
+	// device answers the device authorization endpoint
+	device func(form url.Values) (int, interface{})
+}
+
+// newTokenServer starts the server and points the configuration at it for the duration of the test
+func newTokenServer(t *testing.T) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{keys: map[string]*rsa.PrivateKey{"k1": newKey(t)}, jwksStatus: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks", ts.serveJWKS)
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		writeJSON(w, ts.token, r.PostForm)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		writeJSON(w, ts.device, r.PostForm)
+	})
+	mux.HandleFunc("/api/iam/tenants/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/iam/tenants/")
+		_ = json.NewEncoder(w).Encode(map[string]string{"id": id, "name": "Shop " + id})
+	})
+	ts.Server = httptest.NewServer(mux)
+
+	cfg := config.Default()
+	cfg.ApiURL = ts.URL + "/api"
+	cfg.DeviceAuthorizationURL = ts.URL + "/device"
+	cfg.TokenURL = ts.URL + "/token"
+	cfg.JWKSURL = ts.URL + "/jwks"
+	cfg.Issuer = ts.URL
+	cfg.ClientID = "cli"
+	Configure(cfg)
+	client.Configure(cfg)
+	keys = &keySet{}
+	useTempStore(t)
+
+	t.Cleanup(func() {
+		ts.Close()
+		Configure(config.Default())
+		client.Configure(config.Default())
+		keys = &keySet{}
+	})
+	return ts
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeJSON(w http.ResponseWriter, handler func(url.Values) (int, interface{}), form url.Values) {
+	if handler == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	status, body := handler(form)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (ts *tokenServer) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	atomic.AddInt32(&ts.jwksRequests, 1)
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.jwksStatus != http.StatusOK {
+		w.WriteHeader(ts.jwksStatus)
+		return
+	}
+
+	set := []map[string]string{}
+	for kid, key := range ts.keys {
+		set = append(set, map[string]string{
+			"kid": kid,
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+}
+
+// publish replaces the published signing keys
+func (ts *tokenServer) publish(keys map[string]*rsa.PrivateKey) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.keys = keys
+}
+
+// claims returns valid claims for an access token of the tenant, expiring after ttl
+func (ts *tokenServer) claims(tenantID string, ttl time.Duration) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    ts.URL,
+		"sub":    "user",
+		"tenant": tenantID,
+		"scope":  "openid cnt:files:read",
+		"exp":    time.Now().Add(ttl).Unix(),
+	}
+}
+
+// sign signs the claims with the published key k1
+func (ts *tokenServer) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	ts.mu.Lock()
+	key := ts.keys["k1"]
+	ts.mu.Unlock()
+	return signWith(t, claims, "k1", key)
+}
+
+func signWith(t *testing.T, claims jwt.MapClaims, kid string, key *rsa.PrivateKey) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/afosto/cli/pkg/config"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	CredentialsFile = "credentials"
+	legacyUserFile  = "user.json"
+)
+
+var (
+	ErrorUserNotFound = errors.New("no stored credentials found")
+)
+
+var (
+	storeMu sync.Mutex
+	store   Store
+)
+
+// Store persists authenticated users between runs, keyed by tenant, and the profiles pointing at them
+type Store interface {
+	// Load returns the user stored for the tenant
+	Load(tenantID string) (*data.User, error)
+	Save(user *data.User) error
+	Delete(tenantID string) error
+	Profiles() (*Profiles, error)
+	SaveProfiles(profiles *Profiles) error
+}
+
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+type credentials struct {
+	// Current is the last saved tenant of credentials stored before profiles existed, it becomes the default profile
+	Current  string                `json:"current,omitempty"`
+	Users    map[string]*data.User `json:"users"`
+	Profiles *Profiles             `json:"profiles"`
+}
+
+// GetStore returns the store used to persist users, defaulting to the credentials file in the config dir
+func GetStore() Store {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if store == nil {
+		path, err := DefaultStorePath()
+		if err != nil {
+			logging.Log.Fatal(err)
+		}
+		store = NewFileStore(path)
+	}
+	return store
+}
+
+// SetStore replaces the store used to persist users
+func SetStore(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// DefaultStorePath returns the credentials file path within the config dir (~/.config/afosto/credentials)
+func DefaultStorePath() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, CredentialsFile), nil
+}
+
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (fs *fileStore) Load(tenantID string) (*data.User, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	creds, err := fs.read()
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := creds.Users[tenantID]; ok && u != nil {
+		return u, nil
+	}
+	return nil, ErrorUserNotFound
+}
+
+func (fs *fileStore) Save(user *data.User) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	creds, err := fs.read()
+	if err != nil {
+		return err
+	}
+	creds.Users[user.TenantID] = user
+
+	return fs.write(creds)
+}
+
+func (fs *fileStore) Delete(tenantID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	creds, err := fs.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds.Users[tenantID]; !ok {
+		return ErrorUserNotFound
+	}
+	delete(creds.Users, tenantID)
+	for name, profileTenantID := range creds.Profiles.Tenants {
+		if profileTenantID == tenantID {
+			delete(creds.Profiles.Tenants, name)
+		}
+	}
+	if _, ok := creds.Profiles.Tenants[creds.Profiles.Current]; !ok {
+		creds.Profiles.Current = ""
+	}
+
+	return fs.write(creds)
+}
+
+func (fs *fileStore) Profiles() (*Profiles, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	creds, err := fs.read()
+	if err != nil {
+		return nil, err
+	}
+	return creds.Profiles, nil
+}
+
+func (fs *fileStore) SaveProfiles(profiles *Profiles) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	creds, err := fs.read()
+	if err != nil {
+		return err
+	}
+	creds.Profiles = profiles
+
+	return fs.write(creds)
+}
+
+func (fs *fileStore) read() (*credentials, error) {
+	creds := &credentials{}
+	b, err := ioutil.ReadFile(fs.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	} else if err == nil {
+		if err := json.Unmarshal(b, creds); err != nil {
+			return nil, err
+		}
+	}
+	if creds.Users == nil {
+		creds.Users = map[string]*data.User{}
+	}
+	if creds.Profiles == nil {
+		creds.Profiles = &Profiles{}
+	}
+	if creds.Profiles.Tenants == nil {
+		creds.Profiles.Tenants = map[string]string{}
+	}
+	if creds.Current != "" {
+		if _, ok := creds.Users[creds.Current]; ok && len(creds.Profiles.Tenants) == 0 {
+			creds.Profiles.Tenants[DefaultProfile] = creds.Current
+		}
+		creds.Current = ""
+	}
+	return creds, nil
+}
+
+// write replaces the credentials file atomically, so a failed write never leaves a truncated file behind
+func (fs *fileStore) write(creds *credentials) error {
+	b, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(fs.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, CredentialsFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.path)
+}
+
+// migrateLegacyUser moves a user.json left in the working directory by older versions into the store
+func migrateLegacyUser(s Store) {
+	b, err := ioutil.ReadFile(legacyUserFile)
+	if err != nil {
+		return
+	}
+	legacyUser := data.User{}
+	if err := json.Unmarshal(b, &legacyUser); err != nil || legacyUser.TenantID == "" {
+		logging.Log.Warnf("✗ could not migrate `%s`, remove it manually", legacyUserFile)
+		return
+	}
+	if _, err := s.Load(legacyUser.TenantID); errors.Is(err, ErrorUserNotFound) {
+		if err := s.Save(&legacyUser); err != nil {
+			logging.Log.Error(err)
+			return
+		}
+	} else if err != nil {
+		logging.Log.Error(err)
+		return
+	}
+	profiles, err := s.Profiles()
+	if err != nil {
+		logging.Log.Error(err)
+		return
+	}
+	if _, ok := profiles.Tenants[DefaultProfile]; !ok {
+		profiles.Tenants[DefaultProfile] = legacyUser.TenantID
+		if err := s.SaveProfiles(profiles); err != nil {
+			logging.Log.Error(err)
+			return
+		}
+	}
+	if err := os.Remove(legacyUserFile); err != nil {
+		logging.Log.Warnf("✗ migrated `%s` but could not remove it: %s", legacyUserFile, err)
+		return
+	}
+	logging.Log.Infof("✔ Moved credentials from `%s` into the credential store", legacyUserFile)
+}
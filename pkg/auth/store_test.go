@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"github.com/afosto/cli/pkg/data"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "afosto", CredentialsFile)
+	s := NewFileStore(path)
+
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		u := &data.User{TenantID: tenantID, Name: "Jane"}
+		u.SetAccessToken("token-" + tenantID)
+		if err := s.Save(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := s.Load("tenant-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Jane" || u.GetAccessToken() != "token-tenant-a" {
+		t.Errorf("expected the stored user of tenant-a, got %+v", u)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the credentials to be readable by the owner only, got %s", info.Mode())
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("expected only the credentials file, got %d files", len(files))
+	}
+
+	if err := s.Delete("tenant-a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load("tenant-a"); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected tenant-a to be deleted, got %v", err)
+	}
+	if _, err := s.Load("tenant-b"); err != nil {
+		t.Errorf("expected tenant-b to be kept, got %v", err)
+	}
+	if err := s.Delete("tenant-a"); !errors.Is(err, ErrorUserNotFound) {
+		t.Errorf("expected deleting a missing tenant to fail, got %v", err)
+	}
+}
+
+func TestFileStoreKeepsFileOnFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, CredentialsFile)
+	s := NewFileStore(path)
+	if err := s.Save(&data.User{TenantID: "tenant"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := ioutil.ReadFile(path)
+
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0700)
+	if os.Getuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+
+	if err := s.Save(&data.User{TenantID: "other"}); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	after, _ := ioutil.ReadFile(path)
+	if string(before) != string(after) {
+		t.Errorf("expected the credentials file to be left untouched")
+	}
+}
+
+func TestMigrateLegacyUser(t *testing.T) {
+	s := NewFileStore(filepath.Join(t.TempDir(), CredentialsFile))
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := ioutil.WriteFile(legacyUserFile, []byte(`{"tenant_id": "tenant", "token": "x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	migrateLegacyUser(s)
+
+	u, err := s.Load("tenant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.GetAccessToken() != "x" {
+		t.Errorf("expected the migrated token, got %q", u.GetAccessToken())
+	}
+	if _, err := os.Stat(legacyUserFile); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", legacyUserFile)
+	}
+}
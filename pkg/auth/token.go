@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	ErrorInvalidToken                = errors.New("could not decode token")
+	ErrorInteractiveLoginUnavailable = errors.New("cannot open a browser to log in, use `afosto auth login --device` or supply a token with --token or AFOSTO_TOKEN")
+)
+
+// TokenInfo holds the claims of an access token the cli cares about
+type TokenInfo struct {
+	Subject   string
+	TenantID  string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// DecodeToken reads the claims of an access token without verifying its signature
+func DecodeToken(token string) (*TokenInfo, error) {
+	claims := jwt.MapClaims{}
+	parser := jwt.Parser{}
+	if _, _, err := parser.ParseUnverified(token, claims); err != nil {
+		return nil, ErrorInvalidToken
+	}
+
+	info := &TokenInfo{}
+	if sub, ok := claims["sub"].(string); ok {
+		info.Subject = sub
+	}
+	if tenant, ok := claims["tenant"].(string); ok {
+		info.TenantID = tenant
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		info.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	switch scopes := claims["scope"].(type) {
+	case string:
+		info.Scopes = strings.Fields(scopes)
+	case []interface{}:
+		for _, scope := range scopes {
+			if s, ok := scope.(string); ok {
+				info.Scopes = append(info.Scopes, s)
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// Expired reports whether the token can no longer be used
+func (ti *TokenInfo) Expired() bool {
+	return !ti.ExpiresAt.IsZero() && !time.Now().Before(ti.ExpiresAt)
+}
+
+// GetTokenUser builds a user from an access token supplied from outside, e.g. by a CI pipeline
+func GetTokenUser(accessToken string, tenantID string) (*data.User, error) {
+	u := &data.User{}
+	if err := loadTokens(u, accessToken, ""); err != nil {
+		return nil, err
+	}
+	if tenantID != "" && u.TenantID != tenantID {
+		return nil, fmt.Errorf("%w: token belongs to tenant %s, not %s", ErrorInvalidToken, u.TenantID, tenantID)
+	}
+
+	if err := loadTenant(u); err != nil {
+		logging.Log.Debug(err)
+	}
+
+	return u, nil
+}
+
+// loadTenant fetches the name of the tenant the user belongs to
+func loadTenant(u *data.User) error {
+	tenant, err := client.GetClient(u.TenantID, u.GetAccessToken()).GetTenant()
+	if err != nil {
+		return fmt.Errorf("could not fetch tenant: %w", err)
+	}
+	u.TenantName = tenant.Name
+	return nil
+}
+
+// CanOpenBrowser reports whether an interactive login through the browser is possible
+func CanOpenBrowser() bool {
+	if os.Getenv("CI") != "" {
+		return false
+	}
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd" {
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+	return true
+}
+
+// loadTokenResponse loads the tokens issued by the token endpoint onto the user, along with the granted scopes.
+// When the response does not list the granted scopes, they are the requested ones.
+func loadTokenResponse(u *data.User, token *tokenResponse, requested []string) error {
+	if err := loadTokens(u, token.AccessToken, token.IDToken); err != nil {
+		return err
+	}
+	u.SetRefreshToken(token.RefreshToken)
+	if token.Scope != "" {
+		u.Scopes = strings.Fields(token.Scope)
+	} else if len(u.Scopes) == 0 {
+		u.Scopes = requested
+	}
+	return nil
+}
+
+// loadTokens verifies the tokens and loads their claims onto the user
+func loadTokens(u *data.User, accessToken string, idToken string) error {
+	if accessToken == "" {
+		return ErrorInvalidToken
+	}
+	u.SetAccessToken(accessToken)
+
+	accessTokenClaims, err := verifyToken(accessToken, false)
+	if err != nil {
+		return fmt.Errorf("access token: %w", err)
+	}
+
+	if idToken != "" {
+		idTokenClaims, err := verifyToken(idToken, true)
+		if err != nil {
+			return fmt.Errorf("id token: %w", err)
+		}
+		u.Name, _ = idTokenClaims["name"].(string)
+		u.Email, _ = idTokenClaims["email"].(string)
+	}
+
+	if info, err := DecodeToken(accessToken); err == nil {
+		u.Scopes = info.Scopes
+	}
+	u.TenantID, _ = accessTokenClaims["tenant"].(string)
+	u.ID, _ = accessTokenClaims["sub"].(string)
+	if u.TenantID == "" {
+		return fmt.Errorf("%w: no tenant claim", ErrorInvalidToken)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/dgrijalva/jwt-go"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":    "user",
+		"tenant": "tenant",
+		"scope":  "openid cnt:files:read",
+		"exp":    exp.Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := DecodeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "user" || info.TenantID != "tenant" || !info.ExpiresAt.Equal(exp) || info.Expired() {
+		t.Errorf("unexpected claims %+v", info)
+	}
+	if !reflect.DeepEqual(info.Scopes, []string{"openid", "cnt:files:read"}) {
+		t.Errorf("unexpected scopes %v", info.Scopes)
+	}
+
+	if _, err := DecodeToken("not a token"); !errors.Is(err, ErrorInvalidToken) {
+		t.Errorf("expected an invalid token, got %v", err)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	tests := map[string]struct {
+		expiresAt time.Time
+		expired   bool
+	}{
+		"no expiry": {expired: false},
+		"future":    {expiresAt: time.Now().Add(time.Minute), expired: false},
+		"past":      {expiresAt: time.Now().Add(-time.Minute), expired: true},
+	}
+	for name, test := range tests {
+		if expired := (&TokenInfo{ExpiresAt: test.expiresAt}).Expired(); expired != test.expired {
+			t.Errorf("%s: expected expired to be %v", name, test.expired)
+		}
+	}
+}
+
+func TestMergeScopes(t *testing.T) {
+	merged := MergeScopes([]string{"openid", "email"}, []string{"email", "cnt:files:read"}, nil)
+	if !reflect.DeepEqual(merged, []string{"openid", "email", "cnt:files:read"}) {
+		t.Errorf("unexpected scopes %v", merged)
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	missing := MissingScopes([]string{"openid", "cnt:files:read"}, []string{"cnt:files:read", "cnt:files:write"})
+	if !reflect.DeepEqual(missing, []string{"cnt:files:write"}) {
+		t.Errorf("unexpected scopes %v", missing)
+	}
+}
+
+func TestLoadTokenResponseScopes(t *testing.T) {
+	ts := newTokenServer(t)
+	withScope := ts.claims("tenant", time.Hour)
+	withoutScope := ts.claims("tenant", time.Hour)
+	delete(withoutScope, "scope")
+
+	tests := map[string]struct {
+		claims   jwt.MapClaims
+		scope    string
+		expected []string
+	}{
+		"granted in the response": {withScope, "openid", []string{"openid"}},
+		"granted in the token":    {withScope, "", []string{"openid", "cnt:files:read"}},
+		"requested":               {withoutScope, "", []string{"openid", "email"}},
+	}
+	for name, test := range tests {
+		u := &data.User{}
+		token := &tokenResponse{AccessToken: ts.sign(t, test.claims), RefreshToken: "refresh", Scope: test.scope}
+		if err := loadTokenResponse(u, token, []string{"openid", "email"}); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if !reflect.DeepEqual(u.Scopes, test.expected) || u.GetRefreshToken() != "refresh" {
+			t.Errorf("%s: unexpected user %+v", name, u)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package cli
+
+import (
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// GetClient returns a client for the tenant of the user the command runs as, refreshing its token when it expires
+func GetClient(cmd *cobra.Command, permissions []string, options ...client.Option) *client.AfostoClient {
+	user := GetUser(cmd, permissions)
+	options = append([]client.Option{client.WithTokenSource(auth.TokenSource(user))}, options...)
+	return client.New(user.TenantID, options...)
+}
@@ -1,19 +1,25 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
-// our clean up procedure and exiting the program.
-func CloseHandler() {
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-	func() {
-		<-c
-		fmt.Println("\r- Ctrl+C pressed in Terminal")
-		os.Exit(0)
+// InterruptContext returns a context that is cancelled on Ctrl+C or SIGTERM, a second signal exits immediately
+func InterruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
 	}()
+	return ctx, stop
+}
+
+// our clean up procedure and exiting the program.
+func CloseHandler(ctx context.Context) {
+	<-ctx.Done()
+	fmt.Println("\r- Ctrl+C pressed in Terminal")
 }
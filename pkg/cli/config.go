@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"github.com/afosto/cli/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ConfigEnv = "AFOSTO_CONFIG"
+)
+
+// GetConfigPath returns the configuration file selected with --config or AFOSTO_CONFIG, or the default one
+func GetConfigPath(cmd *cobra.Command) (string, error) {
+	if path := getFlagOrEnv(cmd, "config", ConfigEnv); path != "" {
+		return path, nil
+	}
+	return config.DefaultPath()
+}
+
+// LoadConfig reads the configuration, with the flags of the keys given on the command line taking precedence
+func LoadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, err := GetConfigPath(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := map[string]string{}
+	for _, key := range config.Keys {
+		if flag := cmd.Flags().Lookup(key.Flag()); flag != nil && flag.Changed {
+			overrides[key.Name] = flag.Value.String()
+		}
+	}
+
+	return config.Load(path, overrides)
+}
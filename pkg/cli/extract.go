@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Extract selects values from decoded JSON with a path like .data[].name, where .field selects a field of an
+// object, [n] an item of a list and [] every item of a list. Each selected value is returned.
+func Extract(value interface{}, path string) ([]interface{}, error) {
+	if path == "" || path == "." {
+		return []interface{}{value}, nil
+	}
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		return nil, fmt.Errorf("invalid path `%s`, it starts with . or [", path)
+	}
+
+	values := []interface{}{value}
+	rest := path
+	for rest != "" {
+		var step func(interface{}) ([]interface{}, error)
+		switch {
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid path `%s`, missing ]", path)
+			}
+			index := rest[1:end]
+			rest = rest[end+1:]
+			step = func(v interface{}) ([]interface{}, error) {
+				return extractIndex(v, index)
+			}
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+			field := rest[:end]
+			rest = rest[end:]
+			if field == "" {
+				continue
+			}
+			step = func(v interface{}) ([]interface{}, error) {
+				return extractField(v, field)
+			}
+		default:
+			return nil, fmt.Errorf("invalid path `%s` at `%s`", path, rest)
+		}
+
+		selected := []interface{}{}
+		for _, v := range values {
+			result, err := step(v)
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, result...)
+		}
+		values = selected
+	}
+
+	return values, nil
+}
+
+func extractField(value interface{}, field string) ([]interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return []interface{}{nil}, nil
+	case map[string]interface{}:
+		return []interface{}{v[field]}, nil
+	}
+	return nil, fmt.Errorf("cannot select field `%s` of %s", field, cell(value))
+}
+
+func extractIndex(value interface{}, index string) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{nil}, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select [%s] of %s", index, cell(value))
+	}
+	if index == "" {
+		return list, nil
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index [%s]", index)
+	}
+	if i < 0 {
+		i += len(list)
+	}
+	if i < 0 || i >= len(list) {
+		return []interface{}{nil}, nil
+	}
+	return []interface{}{list[i]}, nil
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(`{"data": [{"name": "a", "tags": ["x", "y"]}, {"name": "b"}], "page": {"after": "c1"}}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string][]interface{}{
+		"":                {value},
+		".":               {value},
+		".page.after":     {"c1"},
+		".data[].name":    {"a", "b"},
+		".data[0].tags[]": {"x", "y"},
+		".data[-1].name":  {"b"},
+		".data[5].name":   {nil},
+		".data[].tags[1]": {"y", nil},
+		".missing.field":  {nil},
+	}
+	for path, expected := range tests {
+		values, err := Extract(value, path)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: expected %v, got %v", path, expected, values)
+		}
+	}
+
+	for _, path := range []string{"data", ".data[", ".data[x]", ".page[0]", ".page.after.field"} {
+		if _, err := Extract(value, path); err == nil {
+			t.Errorf("expected `%s` to be rejected", path)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// ParseFields turns key=value pairs into an object. Values that are JSON literals, like numbers, booleans, null,
+// objects and lists, keep their type, other values are strings. A value of @path reads the value from the file.
+func ParseFields(fields []string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid field `%s`, use key=value", field)
+		}
+		if strings.HasPrefix(value, "@") {
+			b, err := ioutil.ReadFile(strings.TrimPrefix(value, "@"))
+			if err != nil {
+				return nil, err
+			}
+			values[key] = string(b)
+			continue
+		}
+		values[key] = parseValue(value)
+	}
+	return values, nil
+}
+
+// ReadJSONFile decodes the JSON object in the file
+func ReadJSONFile(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func parseValue(value string) interface{} {
+	var typed interface{}
+	if err := json.Unmarshal([]byte(value), &typed); err == nil {
+		if _, isString := typed.(string); !isString {
+			return typed
+		}
+	}
+	return value
+}
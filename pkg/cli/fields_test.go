@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "description.txt")
+	if err := os.WriteFile(path, []byte("line one\nline two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := ParseFields([]string{
+		"name=shirt",
+		"quoted=\"42\"",
+		"price=12.5",
+		"stock=3",
+		"active=true",
+		"parent=null",
+		"tags=[\"a\",\"b\"]",
+		"size={\"width\":2}",
+		"query=a=b",
+		"empty=",
+		"description=@" + path,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name":        "shirt",
+		"quoted":      "\"42\"",
+		"price":       12.5,
+		"stock":       float64(3),
+		"active":      true,
+		"parent":      nil,
+		"tags":        []interface{}{"a", "b"},
+		"size":        map[string]interface{}{"width": float64(2)},
+		"query":       "a=b",
+		"empty":       "",
+		"description": "line one\nline two",
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestParseFieldsInvalid(t *testing.T) {
+	for _, field := range []string{"name", "=value"} {
+		if _, err := ParseFields([]string{field}); err == nil {
+			t.Errorf("expected `%s` to be rejected", field)
+		}
+	}
+	if _, err := ParseFields([]string{"file=@" + filepath.Join(t.TempDir(), "missing")}); !os.IsNotExist(err) {
+		t.Errorf("expected the missing file to be reported, got %v", err)
+	}
+}
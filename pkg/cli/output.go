@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+)
+
+var (
+	OutputFormats = []string{OutputJSON, OutputYAML, OutputTable}
+)
+
+// Print writes the decoded JSON value in the format. A table lists the objects of the first list found by
+// descending into objects with a single field, or else the fields of the value.
+func Print(w io.Writer, value interface{}, format string) error {
+	switch format {
+	case OutputJSON, "":
+		b, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case OutputYAML:
+		b, err := yaml.Marshal(integers(value))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case OutputTable:
+		return printTable(w, value)
+	}
+	return fmt.Errorf("unknown output format `%s`, use one of: %s", format, strings.Join(OutputFormats, ", "))
+}
+
+func printTable(w io.Writer, value interface{}) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	switch v := tableRows(value).(type) {
+	case []interface{}:
+		columns := tableColumns(v)
+		if len(columns) == 0 {
+			for _, row := range v {
+				fmt.Fprintln(tw, cell(row))
+			}
+			break
+		}
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, row := range v {
+			object, _ := row.(map[string]interface{})
+			cells := []string{}
+			for _, column := range columns {
+				cells = append(cells, cell(object[column]))
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			fmt.Fprintf(tw, "%s\t%s\n", key, cell(v[key]))
+		}
+	default:
+		fmt.Fprintln(tw, cell(v))
+	}
+	return tw.Flush()
+}
+
+// tableRows descends into objects with a single field, like the data of a query, to the value worth listing
+func tableRows(value interface{}) interface{} {
+	for {
+		object, ok := value.(map[string]interface{})
+		if !ok || len(object) != 1 {
+			return value
+		}
+		for _, field := range object {
+			value = field
+		}
+	}
+}
+
+// tableColumns returns the sorted fields of the objects in the list
+func tableColumns(rows []interface{}) []string {
+	seen := map[string]interface{}{}
+	for _, row := range rows {
+		if object, ok := row.(map[string]interface{}); ok {
+			for key := range object {
+				seen[key] = nil
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cell renders strings as they are and other values as compact JSON
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
+
+// integers replaces the whole numbers JSON decodes as floats by integers, which yaml would print as 1e+06
+func integers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			object[key] = integers(field)
+		}
+		return object
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = integers(item)
+		}
+		return list
+	}
+	return value
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(`{"products": {"edges": [{"id": "p1", "stock": 1000000}, {"id": "p2", "tags": ["a"]}]}}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		OutputTable: "ID  STOCK    TAGS\np1  1000000  \np2           [\"a\"]\n",
+		OutputYAML:  "products:\n  edges:\n  - id: p1\n    stock: 1000000\n  - id: p2\n    tags:\n    - a\n",
+	}
+	for format, expected := range tests {
+		out := &bytes.Buffer{}
+		if err := Print(out, value, format); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, out.String())
+		}
+	}
+	if err := Print(&bytes.Buffer{}, value, "xml"); err == nil {
+		t.Error("expected an unknown format to be rejected")
+	}
+}
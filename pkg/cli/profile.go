@@ -0,0 +1,14 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+const (
+	ProfileEnv = "AFOSTO_PROFILE"
+)
+
+// GetProfile returns the profile selected with --profile or AFOSTO_PROFILE, empty when none was selected
+func GetProfile(cmd *cobra.Command) string {
+	return getFlagOrEnv(cmd, "profile", ProfileEnv)
+}
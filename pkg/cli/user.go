@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/afosto/cli/pkg/data"
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+const (
+	TokenEnv  = "AFOSTO_TOKEN"
+	TenantEnv = "AFOSTO_TENANT"
+)
+
+// GetUser returns the user the command runs as. A token given with --token or AFOSTO_TOKEN takes precedence
+// over the stored credentials of the selected profile, so pipelines never end up waiting for a browser.
+func GetUser(cmd *cobra.Command, permissions []string) *data.User {
+	if token := getFlagOrEnv(cmd, "token", TokenEnv); token != "" {
+		user, err := auth.GetTokenUser(token, getFlagOrEnv(cmd, "tenant", TenantEnv))
+		if err != nil {
+			logging.Log.Fatal(err)
+		}
+		return user
+	}
+
+	SetCallbackPorts(cmd)
+	user, err := auth.GetProfileUser(GetProfile(cmd), permissions)
+	if err != nil {
+		logging.Log.Fatal(err)
+	}
+	return user
+}
+
+// SetCallbackPorts makes logins started by the command receive the browser redirect on the ports given with --callback-port
+func SetCallbackPorts(cmd *cobra.Command) {
+	if ports, err := cmd.Flags().GetIntSlice("callback-port"); err == nil && len(ports) > 0 {
+		auth.CallbackPorts = ports
+	}
+}
+
+func getFlagOrEnv(cmd *cobra.Command, flag string, env string) string {
+	if value, err := cmd.Flags().GetString(flag); err == nil && value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
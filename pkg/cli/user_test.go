@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"github.com/afosto/cli/pkg/auth"
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+func TestSetCallbackPorts(t *testing.T) {
+	root := &cobra.Command{Use: "afosto"}
+	root.PersistentFlags().IntSlice("callback-port", nil, "")
+	upload := &cobra.Command{Use: "upload", Run: func(cmd *cobra.Command, args []string) {
+		SetCallbackPorts(cmd)
+	}}
+	root.AddCommand(upload)
+	defer func() {
+		auth.CallbackPorts = nil
+	}()
+
+	root.SetArgs([]string{"upload", "--callback-port", "9000,9001"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(auth.CallbackPorts, []int{9000, 9001}) {
+		t.Errorf("expected the ports of the flag, got %v", auth.CallbackPorts)
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrorForeignURL = errors.New("url is not part of the API")
+)
+
+// Request sends an authorized request to the path of the API, or to an absolute URL, and returns the body and
+// headers of the response. Statuses outside the 2xx range return an APIError holding the body.
+func (ac *AfostoClient) Request(ctx context.Context, method string, path string, body io.Reader, header http.Header) ([]byte, http.Header, error) {
+	requestUrl := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		requestUrl = fmt.Sprintf("%s/%s", ac.baseURL, strings.TrimLeft(path, "/"))
+	} else if !strings.HasPrefix(path, ac.baseURL+"/") {
+		// the access token is only ever sent to the API
+		return nil, nil, fmt.Errorf("%w: %s", ErrorForeignURL, path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(method), requestUrl, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	b, headers, err := handle(ac.client.Do(req))
+	return b, headers, err
+}
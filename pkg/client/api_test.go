@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRequest(t *testing.T) {
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cnt/files" || r.Header.Get("x-custom") != "1" || r.Header.Get("authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("x-total", "2")
+		_, _ = w.Write([]byte(`{"data": []}`))
+	}))
+
+	b, header, err := ac.Request(context.Background(), "get", "/cnt/files", nil, http.Header{"X-Custom": {"1"}})
+	if err != nil || string(b) != `{"data": []}` || header.Get("x-total") != "2" {
+		t.Errorf("expected the response, got %s %v: %v", b, header, err)
+	}
+	if _, _, err := ac.Request(context.Background(), "GET", ac.baseURL+"/cnt/files", nil, http.Header{"X-Custom": {"1"}}); err != nil {
+		t.Errorf("expected an absolute URL of the API to be allowed, got %v", err)
+	}
+}
+
+func TestRequestForeignURL(t *testing.T) {
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request")
+	}))
+
+	for _, path := range []string{"https://example.com/cnt/files", ac.baseURL + ".example.com/cnt/files"} {
+		if _, _, err := ac.Request(context.Background(), "GET", path, nil, nil); !errors.Is(err, ErrorForeignURL) {
+			t.Errorf("expected %v for %s, got %v", ErrorForeignURL, path, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/afosto/cli/pkg/data"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// ChunkedUploadThreshold is the size above which files are uploaded in parts
+	ChunkedUploadThreshold int64 = 64 << 20
+	// DefaultPartSize is the size of the parts files are split into, unless the API asks for another size
+	DefaultPartSize int64 = 16 << 20
+)
+
+// UploadProgress describes how far a chunked upload got
+type UploadProgress struct {
+	Filename string
+	// Part is the number of the part that was uploaded, starting at 1
+	Part     int
+	Parts    int
+	Uploaded int64
+	Size     int64
+}
+
+type uploadSession struct {
+	ID       string `json:"id"`
+	PartSize int64  `json:"part_size"`
+}
+
+type uploadedPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// uploadChunked starts an upload session, sends the parts one by one and completes the session into a file. The parts
+// are PUT, so the transport retries each of them on its own after transient failures. A failed upload is aborted so
+// the API can discard the parts it received. When the API does not offer upload sessions, the file is streamed in a
+// single request instead.
+func (ac *AfostoClient) uploadChunked(ctx context.Context, r io.ReaderAt, labelFilename string, size int64, signature string) (*data.File, error) {
+	session, err := ac.startUploadSession(ctx, labelFilename, size, signature)
+	if IsNotFound(err) || hasStatus(err, http.StatusMethodNotAllowed) {
+		ac.logger.Debugf("upload sessions are not available, uploading %s in a single request: %s", labelFilename, err)
+		open := func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(io.NewSectionReader(r, 0, size)), nil
+		}
+		return ac.upload(ctx, open, true, labelFilename, size, signature)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := session.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	parts := int((size + partSize - 1) / partSize)
+
+	uploaded := []uploadedPart{}
+	for number := 1; number <= parts; number++ {
+		offset := int64(number-1) * partSize
+		length := partSize
+		if offset+length > size {
+			length = size - offset
+		}
+
+		part, err := ac.uploadPart(ctx, session, signature, number, io.NewSectionReader(r, offset, length))
+		if err != nil {
+			ac.abortUploadSession(session, signature)
+			return nil, fmt.Errorf("part %d of %d: %w", number, parts, err)
+		}
+		uploaded = append(uploaded, *part)
+
+		if ac.progress != nil {
+			ac.progress(UploadProgress{
+				Filename: labelFilename,
+				Part:     number,
+				Parts:    parts,
+				Uploaded: offset + length,
+				Size:     size,
+			})
+		}
+	}
+
+	file, err := ac.completeUploadSession(ctx, session, signature, uploaded)
+	if err != nil {
+		ac.abortUploadSession(session, signature)
+		return nil, err
+	}
+	return file, nil
+}
+
+func (ac *AfostoClient) startUploadSession(ctx context.Context, labelFilename string, size int64, signature string) (*uploadSession, error) {
+	type request struct {
+		Data struct {
+			Filename string `json:"filename"`
+			Size     int64  `json:"size"`
+			PartSize int64  `json:"part_size"`
+		} `json:"data"`
+	}
+	sessionRequest := request{}
+	sessionRequest.Data.Filename = labelFilename
+	sessionRequest.Data.Size = size
+	sessionRequest.Data.PartSize = DefaultPartSize
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s", ac.baseURL, "storage/files/uploads/"+signature), jsonPayload(sessionRequest))
+	req.Header.Set("content-type", "application/json")
+
+	response := struct {
+		Data uploadSession `json:"data"`
+	}{}
+	b, _, err := handle(ac.client.Do(req))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.ID == "" {
+		return nil, ErrorUploadResponse
+	}
+
+	return &response.Data, nil
+}
+
+func (ac *AfostoClient) uploadPart(ctx context.Context, session *uploadSession, signature string, number int, part *io.SectionReader) (*uploadedPart, error) {
+	requestUrl := fmt.Sprintf("%s/storage/files/uploads/%s/%s/parts/%d", ac.baseURL, signature, session.ID, number)
+	req, _ := http.NewRequestWithContext(ctx, "PUT", requestUrl, part)
+	req.Header.Set("content-type", "application/octet-stream")
+	req.ContentLength = part.Size()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(io.NewSectionReader(part, 0, part.Size())), nil
+	}
+
+	response := struct {
+		Data uploadedPart `json:"data"`
+	}{}
+	b, headers, err := handle(ac.client.Do(req))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.Number == 0 {
+		response.Data.Number = number
+	}
+	if response.Data.ETag == "" {
+		response.Data.ETag = http.Header(headers).Get("etag")
+	}
+
+	return &response.Data, nil
+}
+
+func (ac *AfostoClient) completeUploadSession(ctx context.Context, session *uploadSession, signature string, parts []uploadedPart) (*data.File, error) {
+	type request struct {
+		Data struct {
+			Parts []uploadedPart `json:"parts"`
+		} `json:"data"`
+	}
+	completeRequest := request{}
+	completeRequest.Data.Parts = parts
+
+	requestUrl := fmt.Sprintf("%s/storage/files/uploads/%s/%s/complete", ac.baseURL, signature, session.ID)
+	req, _ := http.NewRequestWithContext(ctx, "POST", requestUrl, jsonPayload(completeRequest))
+	req.Header.Set("content-type", "application/json")
+	// completing twice yields the same file, so the request is safe to repeat
+	req.Header.Set("Idempotency-Key", session.ID+"-complete-"+strconv.Itoa(len(parts)))
+
+	response := struct {
+		Data []data.File `json:"data"`
+	}{}
+	b, _, err := handle(ac.client.Do(req))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Data) == 0 {
+		return nil, ErrorUploadResponse
+	}
+
+	return &response.Data[0], nil
+}
+
+// abortUploadSession discards the parts of a failed upload, also when the upload failed because it was cancelled
+func (ac *AfostoClient) abortUploadSession(session *uploadSession, signature string) {
+	requestUrl := fmt.Sprintf("%s/storage/files/uploads/%s/%s", ac.baseURL, signature, session.ID)
+	req, _ := http.NewRequestWithContext(context.Background(), "DELETE", requestUrl, nil)
+	if _, _, err := handle(ac.client.Do(req)); err != nil {
+		ac.logger.Debugf("could not abort upload %s: %s", session.ID, err)
+	}
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cenkalti/backoff/v4"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// uploadServer stands in for the upload session endpoints, failing the first attempt of the parts in failOnce
+type uploadServer struct {
+	mu       sync.Mutex
+	partSize int64
+	failOnce map[int]bool
+	parts    map[int]string
+	attempts map[int]int
+	complete []uploadedPart
+	aborted  bool
+}
+
+func (us *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/storage/files/uploads/signature")
+	switch {
+	case r.Method == "POST" && path == "":
+		_, _ = w.Write([]byte(`{"data": {"id": "session", "part_size": ` + strconv.FormatInt(us.partSize, 10) + `}}`))
+	case r.Method == "PUT" && strings.HasPrefix(path, "/session/parts/"):
+		number, _ := strconv.Atoi(strings.TrimPrefix(path, "/session/parts/"))
+		us.attempts[number]++
+		if us.failOnce[number] && us.attempts[number] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		us.parts[number] = string(b)
+		w.Header().Set("etag", `"etag-`+strconv.Itoa(number)+`"`)
+		_, _ = w.Write([]byte(`{"data": {}}`))
+	case r.Method == "POST" && path == "/session/complete":
+		request := struct {
+			Data struct {
+				Parts []uploadedPart `json:"parts"`
+			} `json:"data"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		us.complete = request.Data.Parts
+		_, _ = w.Write([]byte(`{"data": [{"filename": "a.txt"}]}`))
+	case r.Method == "DELETE" && path == "/session":
+		us.aborted = true
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func useFastBackOff(t *testing.T) {
+	defaultBackOff := newBackOff
+	newBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), maxRetries)
+	}
+	t.Cleanup(func() {
+		newBackOff = defaultBackOff
+	})
+}
+
+func TestUploadChunked(t *testing.T) {
+	useFastBackOff(t)
+	us := &uploadServer{partSize: 4, failOnce: map[int]bool{2: true}, parts: map[int]string{}, attempts: map[int]int{}}
+	progress := []UploadProgress{}
+	ac := newTestClient(t, us, WithUploadProgress(func(p UploadProgress) {
+		progress = append(progress, p)
+	}))
+
+	contents := "0123456789"
+	file, err := ac.uploadChunked(context.Background(), strings.NewReader(contents), "a.txt", int64(len(contents)), "signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Filename != "a.txt" {
+		t.Errorf("unexpected file %+v", file)
+	}
+
+	if !reflect.DeepEqual(us.parts, map[int]string{1: "0123", 2: "4567", 3: "89"}) {
+		t.Errorf("expected the contents split into parts of 4 bytes, got %v", us.parts)
+	}
+	if !reflect.DeepEqual(us.attempts, map[int]int{1: 1, 2: 2, 3: 1}) {
+		t.Errorf("expected only the failed part to be sent again, got %v", us.attempts)
+	}
+	expected := []uploadedPart{{1, `"etag-1"`}, {2, `"etag-2"`}, {3, `"etag-3"`}}
+	if !reflect.DeepEqual(us.complete, expected) {
+		t.Errorf("expected the session to be completed with the etags of the parts, got %v", us.complete)
+	}
+	if len(progress) != 3 || progress[2].Uploaded != 10 || progress[2].Parts != 3 || us.aborted {
+		t.Errorf("unexpected progress %v", progress)
+	}
+}
+
+func TestUploadChunkedAborts(t *testing.T) {
+	useFastBackOff(t)
+	us := &uploadServer{partSize: 4, failOnce: map[int]bool{}, parts: map[int]string{}, attempts: map[int]int{}}
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" && strings.HasSuffix(r.URL.Path, "/parts/2") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		us.ServeHTTP(w, r)
+	}))
+
+	contents := "0123456789"
+	_, err := ac.uploadChunked(context.Background(), strings.NewReader(contents), "a.txt", int64(len(contents)), "signature")
+	if err == nil || !strings.Contains(err.Error(), "part 2 of 3") {
+		t.Fatalf("expected the second part to fail, got %v", err)
+	}
+	if !us.aborted || us.complete != nil {
+		t.Error("expected the session to be aborted")
+	}
+}
+
+func TestUploadChunkedFallsBack(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusMethodNotAllowed} {
+		uploads := 0
+		ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/storage/files/upload/signature" {
+				w.WriteHeader(status)
+				return
+			}
+			uploads++
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("could not read the form: %v", err)
+				return
+			}
+			if b, _ := ioutil.ReadAll(file); string(b) != "0123456789" {
+				t.Errorf("expected the whole file, got %q", b)
+			}
+			_, _ = w.Write([]byte(`{"data": [{"filename": "a.txt"}]}`))
+		}))
+
+		contents := "0123456789"
+		file, err := ac.uploadChunked(context.Background(), strings.NewReader(contents), "a.txt", int64(len(contents)), "signature")
+		if err != nil {
+			t.Fatalf("%d: %s", status, err)
+		}
+		if file.Filename != "a.txt" || uploads != 1 {
+			t.Errorf("%d: expected a single streaming upload, got %d", status, uploads)
+		}
+	}
+}
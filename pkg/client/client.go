@@ -2,60 +2,66 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/afosto/cli/pkg/config"
 	"github.com/afosto/cli/pkg/data"
 	"github.com/afosto/cli/pkg/logging"
+	"github.com/cenkalti/backoff/v4"
 	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
 )
 
 const (
-	BaseAuthorizationURL = "https://afosto.app/auth/authorize"
-	BaseApiUrl           = "https://afosto.app/api"
-	OauthClientID        = "51403354ded11942d7195c66b9e81f71b74f56cd8adc539277823e179da8"
-	RedirectURL          = "http://localhost:8888/return"
+	// DefaultRateLimit is the number of requests per second a client sends at most, with bursts of DefaultRateBurst
+	DefaultRateLimit = 10
+	DefaultRateBurst = 20
 )
 
 var (
-	cl *AfostoClient
+	settings = config.Default()
 )
 
-type tripper struct {
-	tenantID    string
-	accessToken string
-	rt          http.RoundTripper
-}
+var (
+	ErrorNoRefresh = errors.New("token cannot be refreshed")
+)
 
-type AfostoClient struct {
-	client      *http.Client
-	tenantID    string
-	c           *cache.Cache
-	accessToken string
+// TokenSource supplies the access token for requests and replaces it once the API rejects it
+type TokenSource interface {
+	Token() (string, error)
+	// Refresh returns a new token to replace the rejected one
+	Refresh(expired string) (string, error)
 }
 
-type Query struct {
-	OperationName *string     `json:"operationName"`
-	Query         string      `json:"query"`
-	Variables     interface{} `json:"variables"`
-}
+type staticTokenSource string
 
-type QueryResult struct {
-	Data   map[string]interface{} `json:"data"`
-	Errors []QueryError           `json:"errors"`
+type tripper struct {
+	tenantID  string
+	source    TokenSource
+	userAgent string
+	logger    logrus.FieldLogger
+	limiter   *rateLimiter
+	rt        http.RoundTripper
 }
 
-type QueryError struct {
-	Message string   `json:"message"`
-	Path    []string `json:"path"`
+type AfostoClient struct {
+	client     *http.Client
+	httpClient *http.Client
+	baseURL    string
+	tenantID   string
+	userAgent  string
+	logger     logrus.FieldLogger
+	progress   func(UploadProgress)
+	c          *cache.Cache
+	source     TokenSource
 }
 
 type SignatureRequest struct {
@@ -66,46 +72,82 @@ type SignatureRequest struct {
 	Metadata map[string]string `json:"metadata"`
 }
 
-func GetAuthorizationURL(scopes []string) string {
-	return fmt.Sprintf("%s?client_id=%s&redirect_uri=%s&response_type=token+id_token&scope=%s",
-		BaseAuthorizationURL, OauthClientID, url.QueryEscape(RedirectURL), url.QueryEscape(strings.Join(scopes, " ")))
+// GetAuthorizationURL returns the URL starting the authorization code flow, secured with the PKCE code challenge
+func GetAuthorizationURL(scopes []string, redirectURL string, codeChallenge string, state string) string {
+	return fmt.Sprintf("%s?client_id=%s&redirect_uri=%s&response_type=code&scope=%s&code_challenge=%s&code_challenge_method=S256&state=%s",
+		settings.AuthorizationURL, settings.ClientID, url.QueryEscape(redirectURL), url.QueryEscape(strings.Join(scopes, " ")), url.QueryEscape(codeChallenge), url.QueryEscape(state))
 }
 
+// Configure points the clients at the endpoints of the configuration
+func Configure(cfg *config.Config) {
+	settings = cfg
+}
+
+// GetClient returns a new client for the tenant authorized with the access token
 func GetClient(tenantID string, accessToken string) *AfostoClient {
-	if cl == nil {
-		cl = &AfostoClient{
-			tenantID:    tenantID,
-			accessToken: accessToken,
-			c:           cache.New(time.Minute*5, time.Minute),
-		}
-		cl.client = &http.Client{
-			Timeout: time.Second * 30,
-			Transport: &tripper{
-				accessToken: accessToken,
-				tenantID:    tenantID,
-				rt:          http.DefaultTransport,
-			},
-		}
+	return New(tenantID, WithToken(accessToken))
+}
+
+// GetClientWithTokenSource returns a new client for the tenant authorized with the tokens of the source
+func GetClientWithTokenSource(tenantID string, source TokenSource) *AfostoClient {
+	return New(tenantID, WithTokenSource(source))
+}
+
+// newHTTPClient wraps the transport of the configured http client, or a default one, in the retrying tripper
+func (ac *AfostoClient) newHTTPClient() *http.Client {
+	hc := &http.Client{}
+	if ac.httpClient != nil {
+		*hc = *ac.httpClient
 	}
+	rt := hc.Transport
+	if rt == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		// the transport bounds each attempt, as a timeout on the client would include the waits between retries
+		transport.ResponseHeaderTimeout = time.Second * 30
+		rt = transport
+	}
+	hc.Transport = &tripper{
+		tenantID:  ac.tenantID,
+		source:    ac.source,
+		userAgent: ac.userAgent,
+		logger:    ac.logger,
+		limiter:   newRateLimiter(DefaultRateLimit, DefaultRateBurst),
+		rt:        rt,
+	}
+	return hc
+}
 
-	return cl
+func newSignatureCache() *cache.Cache {
+	return cache.New(time.Minute*5, time.Minute)
 }
 
 func (ac *AfostoClient) GetTenant() (*data.Tenant, error) {
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", BaseApiUrl, "iam/tenants/"+ac.tenantID), nil)
+	return ac.GetTenantContext(context.Background())
+}
+
+// GetTenantContext returns the tenant of the client
+func (ac *AfostoClient) GetTenantContext(ctx context.Context) (*data.Tenant, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%s", ac.baseURL, "iam/tenants/"+ac.tenantID), nil)
 	var tenant data.Tenant
 	b, _, err := handle(ac.client.Do(req))
 	if err != nil {
 		return nil, err
 	}
-	_ = json.Unmarshal(b, &tenant)
+	if err := json.Unmarshal(b, &tenant); err != nil {
+		return nil, err
+	}
 
 	return &tenant, nil
 
 }
 
 func (ac *AfostoClient) GetSignature(dir string, method string, asPrivateDirectory bool) (string, error) {
-	tenant, err := ac.GetTenant()
+	return ac.GetSignatureContext(context.Background(), dir, method, asPrivateDirectory)
+}
+
+// GetSignatureContext returns the signature to upload into the dir, cached per tenant and dir
+func (ac *AfostoClient) GetSignatureContext(ctx context.Context, dir string, method string, asPrivateDirectory bool) (string, error) {
+	tenant, err := ac.GetTenantContext(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -126,7 +168,7 @@ func (ac *AfostoClient) GetSignature(dir string, method string, asPrivateDirecto
 			},
 		}
 
-		req, _ := http.NewRequest("POST", fmt.Sprintf("%s/%s", BaseApiUrl, "storage/files/signature"), jsonPayload(signatureRequest))
+		req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s", ac.baseURL, "storage/files/signature"), jsonPayload(signatureRequest))
 		req.Header.Set("content-type", "application/json")
 
 		type response struct {
@@ -152,50 +194,26 @@ func (ac *AfostoClient) GetSignature(dir string, method string, asPrivateDirecto
 	return signature, nil
 }
 
-func (ac *AfostoClient) ListDirectory(dir string, cursor string) ([]data.File, string, error) {
-
-	requestUrl := fmt.Sprintf("%s/%s?filter[dir][eq]=%s&page[size]=%d", BaseApiUrl, "storage/files", dir, 25)
-
-	if cursor != "" {
-
-		requestUrl = requestUrl + "&page[after]=" + cursor
-	}
-
-	req, _ := http.NewRequest("GET", requestUrl, nil)
-
-	response := struct {
-		Data []data.File `json:"data"`
-		Page struct {
-			After string `json:"after"`
-		} `json:"page"`
-	}{}
-
-	b, _, err := handle(ac.client.Do(req))
-	if err != nil {
-		return nil, "", err
-	}
-
-	_ = json.Unmarshal(b, &response)
-
-	return response.Data, response.Page.After, nil
+func (ac *AfostoClient) ListDirectories(dir string) ([]string, error) {
+	return ac.ListDirectoriesContext(context.Background(), dir)
 }
 
-func (ac *AfostoClient) ListDirectories(dir string) ([]string, error) {
+// ListDirectoriesContext returns the directories starting with dir
+func (ac *AfostoClient) ListDirectoriesContext(ctx context.Context, dir string) ([]string, error) {
 
-	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%s", BaseApiUrl, "storage/directories"), nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%s", ac.baseURL, "storage/directories"), nil)
 
 	directories := struct {
 		Directories []string `json:"data"`
 	}{}
 
-	resp, err := ac.client.Do(req)
-
-	_ = resp
-	b, _, err := handle(resp, err)
+	b, _, err := handle(ac.client.Do(req))
 	if err != nil {
 		return nil, err
 	}
-	_ = json.Unmarshal(b, &directories)
+	if err := json.Unmarshal(b, &directories); err != nil {
+		return nil, err
+	}
 
 	list := []string{}
 
@@ -207,79 +225,103 @@ func (ac *AfostoClient) ListDirectories(dir string) ([]string, error) {
 	return list, nil
 }
 
-func (ac *AfostoClient) Upload(sourceFilePath string, labelFilename string, signature string) (*data.File, error) {
-	file, err := os.Open(sourceFilePath)
-	if err != nil {
-		logging.Log.Error(err)
-		return nil, err
-	}
-	defer file.Close()
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", labelFilename)
+// RoundTrip sends the request within the rate limit, repeating it with exponential backoff after transient failures
+func (ac *tripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	b := newBackOff()
+	attempt := request
+	for {
+		if err := ac.limiter.Wait(request.Context()); err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		logging.Log.Error(err)
-		return nil, err
-	}
-	_, _ = io.Copy(part, file)
-	_ = writer.Close()
+		res, err := ac.authorizedRoundTrip(attempt)
+		if !isRetryable(request, res, err) {
+			return res, err
+		}
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			return res, err
+		}
+		if delay := retryAfter(res); delay > 0 {
+			wait = delay
+		}
+		if res != nil {
+			ac.logger.WithField("retrying in", wait).Warnf("%s %s: %s", request.Method, redactURL(request.URL), res.Status)
+			_, _ = io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		} else {
+			ac.logger.WithField("retrying in", wait).Warnf("%s %s: %s", request.Method, redactURL(request.URL), err)
+		}
 
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/%s", BaseApiUrl, "storage/files/upload/"+signature), body)
-	req.Header.Set("content-type", writer.FormDataContentType())
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-request.Context().Done():
+			timer.Stop()
+			return nil, request.Context().Err()
+		}
 
-	type response struct {
-		Data []data.File `json:"data"`
+		if attempt, err = rewind(request); err != nil {
+			return nil, err
+		}
 	}
+}
 
-	var fileResponse response
-	b, _, err := handle(ac.client.Do(req))
+// rewind returns a copy of the request with a fresh body, to send it again
+func rewind(request *http.Request) (*http.Request, error) {
+	retry := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return retry, nil
+}
 
+// authorizedRoundTrip sends the request with the access token, refreshing the token once when the API rejects it
+func (ac *tripper) authorizedRoundTrip(request *http.Request) (*http.Response, error) {
+	token, err := ac.source.Token()
 	if err != nil {
 		return nil, err
 	}
-
-	_ = json.Unmarshal(b, &fileResponse)
-
-	if len(fileResponse.Data) > 0 {
-		return &fileResponse.Data[0], nil
+	request.Header.Set("authorization", "Bearer "+token)
+	if ac.userAgent != "" {
+		request.Header.Set("user-agent", ac.userAgent)
 	}
 
-	return nil, errors.New("got wrong response")
-}
-
-func (ac *AfostoClient) Download(url *url.URL) ([]byte, error) {
-	req, _ := http.NewRequest("GET", url.String(), nil)
-	b, _, err := handle(ac.client.Do(req))
-	return b, err
-}
-
-func (ac *AfostoClient) Query(query string, parameters interface{}) (*QueryResult, error) {
-	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/%s", BaseApiUrl, "gql"), jsonPayload(Query{
-		OperationName: nil,
-		Query:         query,
-		Variables:     parameters,
-	}))
-
-	req.Header.Set("content-type", "application/json")
+	res, err := ac.rt.RoundTrip(request)
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
 
-	var result QueryResult
-	b, _, err := handle(ac.client.Do(req))
+	// the body was consumed by the first attempt, so only requests that can rewind it are retried
+	if request.Body != nil && request.GetBody == nil {
+		return res, nil
+	}
+	refreshed, err := ac.source.Refresh(token)
 	if err != nil {
-		return nil, err
+		return res, nil
 	}
-	_ = json.Unmarshal(b, &result)
 
-	return &result, nil
+	retry, err := rewind(request)
+	if err != nil {
+		return res, nil
+	}
+	retry.Header.Set("authorization", "Bearer "+refreshed)
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
 
+	return ac.rt.RoundTrip(retry)
 }
 
-func (ac *tripper) RoundTrip(request *http.Request) (*http.Response, error) {
-	request.Header.Set("authorization", "Bearer "+ac.accessToken)
-
-	return ac.rt.RoundTrip(request)
+func (s staticTokenSource) Token() (string, error) {
+	return string(s), nil
+}
 
+func (s staticTokenSource) Refresh(_ string) (string, error) {
+	return "", ErrorNoRefresh
 }
 
 func jsonPayload(payload interface{}) *bytes.Reader {
@@ -290,8 +332,15 @@ func jsonPayload(payload interface{}) *bytes.Reader {
 	return bytes.NewReader(b)
 }
 
+// handle reads the response, turning statuses outside the 2xx range into an APIError
 func handle(res *http.Response, err error) ([]byte, map[string][]string, error) {
 	if err != nil {
+		var urlError *url.Error
+		if errors.As(err, &urlError) {
+			if u, parseErr := url.Parse(urlError.URL); parseErr == nil {
+				urlError.URL = redactURL(u)
+			}
+		}
 		return nil, nil, err
 	}
 
@@ -305,5 +354,9 @@ func handle(res *http.Response, err error) ([]byte, map[string][]string, error)
 		headers[key] = values
 	}
 
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return b, headers, newAPIError(res, b)
+	}
+
 	return b, headers, nil
 }
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client talking to the handler, without logging its retries
+func newTestClient(t *testing.T, handler http.Handler, options ...Option) *AfostoClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	options = append([]Option{WithBaseURL(server.URL), WithToken("token"), WithLogger(logger)}, options...)
+	return New("tenant", options...)
+}
+
+func TestNewOptions(t *testing.T) {
+	var header http.Header
+	var path string
+	hc := &http.Client{Timeout: time.Second * 5}
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header, path = r.Header, r.URL.Path
+		_, _ = w.Write([]byte(`{"id": "tenant", "name": "Shop"}`))
+	}), WithHTTPClient(hc), WithUserAgent("deploy-bot"), WithToken("other"))
+
+	if _, err := ac.GetTenant(); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/iam/tenants/tenant" || header.Get("authorization") != "Bearer other" || header.Get("user-agent") != "deploy-bot" {
+		t.Errorf("unexpected request %s %v", path, header)
+	}
+	if ac.client.Timeout != hc.Timeout || hc.Transport != nil {
+		t.Error("expected the timeout of the http client to be kept without changing it")
+	}
+}
+
+func TestContextCancelsRequest(t *testing.T) {
+	release := make(chan bool)
+	defer close(release)
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	if _, err := ac.GetTenantContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to be cancelled, got %v", err)
+	}
+}
+
+func TestContextCancelsRetry(t *testing.T) {
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	start := time.Now()
+	if _, err := ac.GetTenantContext(ctx); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second*5 {
+		t.Errorf("expected the wait before the retry to be cancelled, got %v", err)
+	}
+}
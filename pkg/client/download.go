@@ -0,0 +1,293 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/cenkalti/backoff/v4"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PartialSuffix is appended to the path of files that are still being downloaded
+	PartialSuffix = ".part"
+	// validatorSuffix is appended to the partial file for the etag or last-modified date it was downloaded with
+	validatorSuffix = ".validator"
+)
+
+var (
+	ErrorIncompleteDownload = errors.New("download is incomplete")
+	ErrorChecksumMismatch   = errors.New("downloaded file does not match its etag")
+)
+
+var (
+	// md5ETag matches the etags storage backends compute as the MD5 of the file
+	md5ETag = regexp.MustCompile(`^"?([0-9a-fA-F]{32})"?$`)
+	// contentRange matches the start of the range and the total length in a Content-Range header
+	contentRange = regexp.MustCompile(`^bytes (\d+)-\d+/(\d+|\*)$`)
+)
+
+// DownloadResult describes the file written by DownloadTo
+type DownloadResult struct {
+	// Written is the number of bytes written
+	Written int64
+	// ContentLength is the length the server announced, or -1
+	ContentLength int64
+	ETag          string
+	// Resumed is the number of bytes a resumed download started from
+	Resumed int64
+}
+
+func (ac *AfostoClient) Download(url *url.URL) ([]byte, error) {
+	return ac.DownloadContext(context.Background(), url)
+}
+
+// DownloadContext returns the contents of the file at url
+func (ac *AfostoClient) DownloadContext(ctx context.Context, url *url.URL) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if _, err := ac.DownloadTo(ctx, url, buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// DownloadTo streams the file at url into the writer. It fails when fewer bytes arrive than the server announced,
+// or when the contents do not match an etag that holds the MD5 of the file.
+func (ac *AfostoClient) DownloadTo(ctx context.Context, url *url.URL, w io.Writer) (*DownloadResult, error) {
+	res, err := ac.get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	download := &DownloadResult{
+		ContentLength: res.ContentLength,
+		ETag:          res.Header.Get("etag"),
+	}
+	checksum, expected := newChecksum(res)
+	if checksum != nil {
+		w = io.MultiWriter(w, checksum)
+	}
+	if err := download.copy(w, res.Body); err != nil {
+		return download, err
+	}
+
+	return download, download.verify(checksum, expected)
+}
+
+// DownloadFile writes the file at url to path. The file is written to path.part and only renamed into place once it
+// is complete, so a failed download never leaves a truncated file behind. An interrupted download resumes from the
+// partial file with a range request, as long as the file did not change on the server since.
+func (ac *AfostoClient) DownloadFile(ctx context.Context, url *url.URL, path string) (*DownloadResult, error) {
+	partial := path + PartialSuffix
+
+	b := newBackOff()
+	for {
+		download, err := ac.downloadPartial(ctx, url, partial)
+		if err == nil {
+			_ = os.Remove(partial + validatorSuffix)
+			return download, os.Rename(partial, path)
+		}
+		if errors.Is(err, ErrorChecksumMismatch) || IsNotFound(err) {
+			removePartial(partial)
+			return download, err
+		}
+
+		// only a connection dropping halfway is worth resuming right away, the transport retried everything else
+		if download == nil || ctx.Err() != nil {
+			return download, err
+		}
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			return download, err
+		}
+		ac.logger.WithField("retrying in", wait).Warnf("GET %s: %s", redactURL(url), err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return download, ctx.Err()
+		}
+	}
+}
+
+// downloadPartial appends the rest of the file to the partial file, or starts it over when it cannot be resumed.
+// The partial file is only created once the server responds with the file.
+func (ac *AfostoClient) downloadPartial(ctx context.Context, url *url.URL, partial string) (*DownloadResult, error) {
+	offset := int64(0)
+	if info, err := os.Stat(partial); err == nil {
+		offset = info.Size()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	validator, _ := ioutil.ReadFile(partial + validatorSuffix)
+
+	header := http.Header{}
+	if offset > 0 && len(validator) > 0 {
+		header.Set("range", fmt.Sprintf("bytes=%d-", offset))
+		header.Set("if-range", string(validator))
+	}
+
+	res, err := ac.get(ctx, url, header)
+	if IsRangeNotSatisfiable(err) && header.Get("range") != "" {
+		// the partial file is not a prefix of the file on the server anymore
+		res, err = ac.get(ctx, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(partial), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_RDWR, 0664)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	download := &DownloadResult{
+		ContentLength: res.ContentLength,
+		ETag:          res.Header.Get("etag"),
+	}
+	checksum, expected := newChecksum(res)
+
+	if start, total, ok := resumedRange(res); ok && start == offset {
+		download.Resumed = offset
+		download.ContentLength = total
+		if checksum != nil {
+			if _, err := io.Copy(checksum, io.LimitReader(file, offset)); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	} else if res.StatusCode == http.StatusPartialContent {
+		return nil, fmt.Errorf("%w: unexpected range `%s`", ErrorIncompleteDownload, res.Header.Get("content-range"))
+	} else {
+		// the server sent the whole file, because it ignores ranges or the file changed
+		if err := file.Truncate(0); err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		storeValidator(partial, res)
+	}
+
+	var w io.Writer = file
+	if checksum != nil {
+		w = io.MultiWriter(file, checksum)
+	}
+	err = download.copy(w, res.Body)
+	download.Written += download.Resumed
+	if err != nil {
+		return download, err
+	}
+	if err := download.verify(checksum, expected); err != nil {
+		return download, err
+	}
+
+	return download, file.Close()
+}
+
+// get requests the file, returning an APIError for statuses outside the 2xx range
+func (ac *AfostoClient) get(ctx context.Context, url *url.URL, header http.Header) (*http.Response, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	res, err := ac.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return nil, newAPIError(res, b)
+	}
+	return res, nil
+}
+
+// resumedRange returns the start and total length of a partial response
+func resumedRange(res *http.Response) (int64, int64, bool) {
+	if res.StatusCode != http.StatusPartialContent {
+		return 0, 0, false
+	}
+	match := contentRange.FindStringSubmatch(res.Header.Get("content-range"))
+	if match == nil {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total, err := strconv.ParseInt(match[2], 10, 64)
+	if err != nil {
+		total = -1
+	}
+	return start, total, true
+}
+
+// storeValidator keeps the strong etag, or else the last-modified date, to resume the partial file with
+func storeValidator(partial string, res *http.Response) {
+	validator := res.Header.Get("etag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = res.Header.Get("last-modified")
+	}
+	if validator == "" || res.Uncompressed {
+		_ = os.Remove(partial + validatorSuffix)
+		return
+	}
+	_ = ioutil.WriteFile(partial+validatorSuffix, []byte(validator), 0664)
+}
+
+func removePartial(partial string) {
+	_ = os.Remove(partial)
+	_ = os.Remove(partial + validatorSuffix)
+}
+
+// newChecksum returns a hash to verify the body with, when the etag is the MD5 of the file
+func newChecksum(res *http.Response) (hash.Hash, string) {
+	// a body the transport decompressed no longer matches the etag computed over the stored file
+	if match := md5ETag.FindStringSubmatch(res.Header.Get("etag")); match != nil && !res.Uncompressed {
+		return md5.New(), strings.ToLower(match[1])
+	}
+	return nil, ""
+}
+
+func (d *DownloadResult) copy(w io.Writer, body io.Reader) error {
+	var err error
+	d.Written, err = io.Copy(w, body)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrorIncompleteDownload, d.Resumed+d.Written, d.ContentLength)
+	}
+	return err
+}
+
+func (d *DownloadResult) verify(checksum hash.Hash, expected string) error {
+	if d.ContentLength >= 0 && d.Written != d.ContentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrorIncompleteDownload, d.Written, d.ContentLength)
+	}
+	if checksum != nil && hex.EncodeToString(checksum.Sum(nil)) != expected {
+		return ErrorChecksumMismatch
+	}
+	return nil
+}
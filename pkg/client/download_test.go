@@ -0,0 +1,182 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"github.com/cenkalti/backoff/v4"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveFile answers with the contents, announcing the length and the etag
+func serveFile(contents string, length string, etag string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-length", length)
+		w.Header().Set("etag", etag)
+		_, _ = w.Write([]byte(contents))
+	})
+}
+
+func md5ETagOf(contents string) string {
+	sum := md5.Sum([]byte(contents))
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func TestDownloadTo(t *testing.T) {
+	ac := newTestClient(t, serveFile("contents", "8", md5ETagOf("contents")))
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	buffer := &bytes.Buffer{}
+	download, err := ac.DownloadTo(context.Background(), u, buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != "contents" || download.Written != 8 || download.ContentLength != 8 {
+		t.Errorf("unexpected download %+v of %q", download, buffer.String())
+	}
+}
+
+func TestDownloadToChecksumMismatch(t *testing.T) {
+	ac := newTestClient(t, serveFile("contents", "8", md5ETagOf("other")))
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	if _, err := ac.DownloadTo(context.Background(), u, ioutil.Discard); !errors.Is(err, ErrorChecksumMismatch) {
+		t.Errorf("expected %v, got %v", ErrorChecksumMismatch, err)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	ac := newTestClient(t, serveFile("contents", "8", `"not-an-md5"`))
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "a.txt")
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	if _, err := ac.DownloadFile(context.Background(), u, path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil || string(b) != "contents" {
+		t.Fatalf("expected the contents, got %q: %v", b, err)
+	}
+	if files, _ := ioutil.ReadDir(filepath.Dir(path)); len(files) != 1 {
+		t.Errorf("expected only the renamed file, got %d files", len(files))
+	}
+}
+
+func TestDownloadFileFails(t *testing.T) {
+	tests := map[string]struct {
+		handler http.Handler
+		partial string
+	}{
+		"not found": {handler: http.NotFoundHandler()},
+		"checksum":  {handler: serveFile("contents", "8", md5ETagOf("other"))},
+		// the connection closes before the announced length arrived, so the part that did arrive is kept to resume
+		"incomplete": {handler: serveFile("contents", "10", `"v1"`), partial: "contents"},
+	}
+	defaultBackOff := newBackOff
+	newBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), maxRetries)
+	}
+	defer func() {
+		newBackOff = defaultBackOff
+	}()
+
+	for name, test := range tests {
+		ac := newTestClient(t, test.handler)
+		path := filepath.Join(t.TempDir(), "a.txt")
+		if err := ioutil.WriteFile(path, []byte("previous"), 0664); err != nil {
+			t.Fatal(err)
+		}
+
+		u, _ := url.Parse(ac.baseURL + "/a.txt")
+		if _, err := ac.DownloadFile(context.Background(), u, path); err == nil {
+			t.Errorf("%s: expected the download to fail", name)
+		}
+		if b, _ := ioutil.ReadFile(path); string(b) != "previous" {
+			t.Errorf("%s: expected the existing file to be kept, got %q", name, b)
+		}
+		if b, err := ioutil.ReadFile(path + PartialSuffix); string(b) != test.partial || (test.partial == "" && !os.IsNotExist(err)) {
+			t.Errorf("%s: expected %q to be kept to resume, got %q", name, test.partial, b)
+		}
+	}
+}
+
+func TestDownloadFileNotFound(t *testing.T) {
+	ac := newTestClient(t, http.NotFoundHandler())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing", "a.txt")
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	if _, err := ac.DownloadFile(context.Background(), u, path); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written, got %v", err)
+	}
+}
+
+func TestDownloadFileResumes(t *testing.T) {
+	contents := strings.Repeat("0123456789", 100)
+	ranges := []string{}
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("range"))
+		w.Header().Set("etag", `"v1"`)
+		http.ServeContent(w, r, "a.txt", time.Time{}, strings.NewReader(contents))
+	}))
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := ioutil.WriteFile(path+PartialSuffix, []byte(contents[:400]), 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path+PartialSuffix+validatorSuffix, []byte(`"v1"`), 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	download, err := ac.DownloadFile(context.Background(), u, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if download.Resumed != 400 || download.Written != int64(len(contents)) || ranges[0] != "bytes=400-" {
+		t.Errorf("expected the download to resume after 400 bytes, got %+v with %v", download, ranges)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil || !bytes.Equal(b, []byte(contents)) {
+		t.Errorf("expected the complete file, got %d bytes: %v", len(b), err)
+	}
+	if _, err := os.Stat(path + PartialSuffix); !os.IsNotExist(err) {
+		t.Errorf("expected the partial file to be removed")
+	}
+}
+
+func TestDownloadFileRestartsChangedFile(t *testing.T) {
+	ranges := []string{}
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("range"))
+		w.Header().Set("etag", `"v2"`)
+		http.ServeContent(w, r, "a.txt", time.Time{}, strings.NewReader("new contents"))
+	}))
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := ioutil.WriteFile(path+PartialSuffix, []byte("old"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path+PartialSuffix+validatorSuffix, []byte(`"v1"`), 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse(ac.baseURL + "/a.txt")
+	if _, err := ac.DownloadFile(context.Background(), u, path); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "new contents" {
+		t.Errorf("expected the changed file to be downloaded from the start, got %q with %v", b, ranges)
+	}
+}
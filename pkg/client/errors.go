@@ -0,0 +1,120 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+)
+
+var (
+	// signaturePath matches the signature that authorizes an upload, which must not end up in logs
+	signaturePath = regexp.MustCompile(`(/uploads?/)[^/]+`)
+)
+
+// APIError is returned for responses of the API outside the 2xx range
+type APIError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	RequestID  string
+	// Message is the error reported in the payload of the API, if any
+	Message string
+	// Payload is the raw body of the response
+	Payload []byte
+}
+
+func newAPIError(res *http.Response, body []byte) *APIError {
+	apiError := &APIError{
+		StatusCode: res.StatusCode,
+		RequestID:  res.Header.Get(RequestIDHeader),
+		Payload:    body,
+	}
+	if res.Request != nil {
+		apiError.Method = res.Request.Method
+		apiError.URL = redactURL(res.Request.URL)
+	}
+
+	payload := struct {
+		Message          string `json:"message"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		Errors           []struct {
+			Title   string `json:"title"`
+			Detail  string `json:"detail"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		messages := []string{}
+		for _, m := range []string{payload.Message, payload.Error, payload.ErrorDescription} {
+			if m != "" {
+				messages = append(messages, m)
+			}
+		}
+		for _, e := range payload.Errors {
+			for _, m := range []string{e.Title, e.Detail, e.Message} {
+				if m != "" {
+					messages = append(messages, m)
+				}
+			}
+		}
+		apiError.Message = strings.Join(messages, ": ")
+	}
+
+	return apiError
+}
+
+// redactURL returns the URL without its password and upload signature
+func redactURL(u *url.URL) string {
+	return signaturePath.ReplaceAllString(u.Redacted(), "${1}xxxxx")
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// IsNotFound reports whether the API could not find the requested resource
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether the API rejected the access token
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether the access token lacks permission for the request
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsRangeNotSatisfiable reports whether the requested range lies outside the file
+func IsRangeNotSatisfiable(err error) bool {
+	return hasStatus(err, http.StatusRequestedRangeNotSatisfiable)
+}
+
+// IsServerError reports whether the API failed to handle the request
+func IsServerError(err error) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode >= 500
+}
+
+func hasStatus(err error, status int) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode == status
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIError(t *testing.T) {
+	tests := map[string]struct {
+		body     string
+		expected string
+	}{
+		"message":      {`{"message":"Not found"}`, "Not found"},
+		"oauth":        {`{"error":"invalid_token","error_description":"expired"}`, "invalid_token: expired"},
+		"errors":       {`{"errors":[{"title":"Invalid","detail":"dir is required"},{"message":"name is too long"}]}`, "Invalid: dir is required: name is too long"},
+		"not json":     {`<html>Bad gateway</html>`, ""},
+		"empty object": {`{}`, ""},
+	}
+	for name, test := range tests {
+		req := httptest.NewRequest("GET", "https://afosto.app/api/storage/files", nil)
+		res := &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Request: req}
+		res.Header.Set(RequestIDHeader, "req-1")
+
+		err := newAPIError(res, []byte(test.body))
+		if err.Message != test.expected || string(err.Payload) != test.body {
+			t.Errorf("%s: unexpected message %q", name, err.Message)
+		}
+		if err.Method != "GET" || err.URL != "https://afosto.app/api/storage/files" || err.RequestID != "req-1" {
+			t.Errorf("%s: unexpected request %+v", name, err)
+		}
+	}
+}
+
+func TestAPIErrorString(t *testing.T) {
+	err := &APIError{StatusCode: http.StatusForbidden, Method: "POST", URL: "https://afosto.app/api/gql", RequestID: "req-1", Message: "missing scope"}
+	expected := "POST https://afosto.app/api/gql: 403 Forbidden: missing scope (request req-1)"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	wrapped := func(status int) error {
+		return fmt.Errorf("could not list files: %w", &APIError{StatusCode: status})
+	}
+	if !IsNotFound(wrapped(http.StatusNotFound)) || IsNotFound(wrapped(http.StatusForbidden)) {
+		t.Error("expected only a 404 to be not found")
+	}
+	if !IsUnauthorized(wrapped(http.StatusUnauthorized)) || !IsForbidden(wrapped(http.StatusForbidden)) {
+		t.Error("expected the status of a wrapped error to be reported")
+	}
+	if !IsServerError(wrapped(http.StatusBadGateway)) || IsServerError(wrapped(http.StatusBadRequest)) {
+		t.Error("expected only 5xx to be server errors")
+	}
+	if IsNotFound(errors.New("not found")) {
+		t.Error("expected other errors to have no status")
+	}
+}
+
+func TestAPIErrorRedactsSignature(t *testing.T) {
+	tests := map[string]string{
+		"/storage/files/upload/secret-signature":                  "/storage/files/upload/xxxxx",
+		"/storage/files/uploads/secret-signature/session/parts/1": "/storage/files/uploads/xxxxx/session/parts/1",
+	}
+	for path, expected := range tests {
+		req := httptest.NewRequest("POST", "https://afosto.app/api"+path, nil)
+		err := newAPIError(&http.Response{StatusCode: http.StatusBadRequest, Request: req}, nil)
+		if strings.Contains(err.Error(), "secret-signature") || err.URL != "https://afosto.app/api"+expected {
+			t.Errorf("expected the signature to be redacted, got %s", err.URL)
+		}
+	}
+}
+
+func TestHandleRedactsSignature(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	uploadURL := ts.URL + "/storage/files/upload/secret-signature"
+	ts.Close()
+
+	_, _, err := handle(http.Post(uploadURL, "text/plain", nil))
+	if err == nil || strings.Contains(err.Error(), "secret-signature") {
+		t.Errorf("expected the signature to be redacted, got %v", err)
+	}
+}
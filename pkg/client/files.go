@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/afosto/cli/pkg/data"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageSize = 25
+)
+
+// FileFilter selects the files ListFiles returns, zero values do not filter
+type FileFilter struct {
+	Dir  string
+	Mime string
+	// IsPublic selects either the public or the private files
+	IsPublic *bool
+	// Label searches for files whose label contains the text
+	Label         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	// Sort orders the files by the fields, prefix a field with - to sort descending
+	Sort []string
+	// PageSize is the number of files requested at once, DefaultPageSize when zero
+	PageSize int
+}
+
+// FileIterator walks over the files of a listing, requesting the pages as it goes
+//
+//	files := ac.ListFiles(ctx, client.FileFilter{Dir: "uploads"})
+//	for files.Next() {
+//		fmt.Println(files.File().Filename)
+//	}
+//	return files.Err()
+type FileIterator interface {
+	// Next advances to the next file, returning false when the listing is exhausted or failed
+	Next() bool
+	File() data.File
+	// Err returns the error that ended the listing, if any
+	Err() error
+}
+
+type fileIterator struct {
+	ctx    context.Context
+	ac     *AfostoClient
+	filter FileFilter
+	page   []data.File
+	file   data.File
+	cursor string
+	done   bool
+	err    error
+}
+
+func (ac *AfostoClient) ListDirectory(dir string, cursor string) ([]data.File, string, error) {
+	return ac.ListDirectoryContext(context.Background(), dir, cursor)
+}
+
+// ListDirectoryContext returns a page of the files in the dir and the cursor of the next page
+func (ac *AfostoClient) ListDirectoryContext(ctx context.Context, dir string, cursor string) ([]data.File, string, error) {
+	return ac.listFiles(ctx, FileFilter{Dir: dir}, cursor)
+}
+
+// ListFiles returns an iterator over all files matching the filter, following the cursors until the last page
+func (ac *AfostoClient) ListFiles(ctx context.Context, filter FileFilter) FileIterator {
+	return &fileIterator{ctx: ctx, ac: ac, filter: filter}
+}
+
+func (it *fileIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		previous := it.cursor
+		it.page, it.cursor, it.err = it.ac.listFiles(it.ctx, it.filter, it.cursor)
+		if it.err != nil {
+			return false
+		}
+		// a page without a cursor is the last one, a repeated cursor would never end
+		if it.cursor == "" || it.cursor == previous {
+			it.done = true
+		}
+	}
+	it.file, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *fileIterator) File() data.File {
+	return it.file
+}
+
+func (it *fileIterator) Err() error {
+	return it.err
+}
+
+func (ac *AfostoClient) listFiles(ctx context.Context, filter FileFilter, cursor string) ([]data.File, string, error) {
+	params := filter.params()
+	if cursor != "" {
+		params.Page("after", cursor)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", params.URL(fmt.Sprintf("%s/%s", ac.baseURL, "storage/files")), nil)
+
+	response := struct {
+		Data []data.File `json:"data"`
+		Page struct {
+			After string `json:"after"`
+		} `json:"page"`
+	}{}
+
+	b, _, err := handle(ac.client.Do(req))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, "", err
+	}
+
+	return response.Data, response.Page.After, nil
+}
+
+func (filter FileFilter) params() *Params {
+	params := NewParams()
+	if filter.Dir != "" {
+		params.Filter("dir", "eq", filter.Dir)
+	}
+	if filter.Mime != "" {
+		params.Filter("mime", "eq", filter.Mime)
+	}
+	if filter.IsPublic != nil {
+		params.Filter("is_public", "eq", strconv.FormatBool(*filter.IsPublic))
+	}
+	if filter.Label != "" {
+		params.Filter("label", "contains", filter.Label)
+	}
+	for _, r := range []struct {
+		field    string
+		operator string
+		value    time.Time
+	}{
+		{"created_at", "gte", filter.CreatedAfter},
+		{"created_at", "lte", filter.CreatedBefore},
+		{"updated_at", "gte", filter.UpdatedAfter},
+		{"updated_at", "lte", filter.UpdatedBefore},
+	} {
+		if !r.value.IsZero() {
+			params.Filter(r.field, r.operator, r.value.UTC().Format(time.RFC3339))
+		}
+	}
+
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return params.Page("size", strconv.Itoa(pageSize)).Sort(filter.Sort...)
+}
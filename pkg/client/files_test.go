@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// pagedFiles serves the pages of filenames, keyed by the cursor they are requested with, pointing to the next cursor
+func pagedFiles(t *testing.T, pages map[string][]string, next map[string]string, requests *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("page[after]")
+		*requests = append(*requests, cursor)
+		if r.URL.Query().Get("filter[dir][eq]") != "uploads" || r.URL.Query().Get("page[size]") != "2" {
+			t.Errorf("unexpected query %v", r.URL.Query())
+		}
+		filenames, ok := pages[cursor]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		files := []map[string]string{}
+		for _, filename := range filenames {
+			files = append(files, map[string]string{"filename": filename})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": files,
+			"page": map[string]string{"after": next[cursor]},
+		})
+	})
+}
+
+func listAll(it FileIterator) []string {
+	filenames := []string{}
+	for it.Next() {
+		filenames = append(filenames, it.File().Filename)
+	}
+	return filenames
+}
+
+func TestFileIterator(t *testing.T) {
+	tests := map[string]struct {
+		pages    map[string][]string
+		next     map[string]string
+		files    []string
+		requests []string
+	}{
+		"single page": {
+			pages:    map[string][]string{"": {"a", "b"}},
+			files:    []string{"a", "b"},
+			requests: []string{""},
+		},
+		"until the last cursor": {
+			pages:    map[string][]string{"": {"a", "b"}, "c1": {"c", "d"}, "c2": {"e"}},
+			next:     map[string]string{"": "c1", "c1": "c2"},
+			files:    []string{"a", "b", "c", "d", "e"},
+			requests: []string{"", "c1", "c2"},
+		},
+		"over empty pages": {
+			pages:    map[string][]string{"": {}, "c1": {"a"}},
+			next:     map[string]string{"": "c1"},
+			files:    []string{"a"},
+			requests: []string{"", "c1"},
+		},
+		"repeated cursor": {
+			pages:    map[string][]string{"": {"a"}, "c1": {"b"}},
+			next:     map[string]string{"": "c1", "c1": "c1"},
+			files:    []string{"a", "b"},
+			requests: []string{"", "c1"},
+		},
+		"empty directory": {
+			pages:    map[string][]string{"": {}},
+			files:    []string{},
+			requests: []string{""},
+		},
+	}
+	for name, test := range tests {
+		requests := []string{}
+		ac := newTestClient(t, pagedFiles(t, test.pages, test.next, &requests))
+
+		it := ac.ListFiles(context.Background(), FileFilter{Dir: "uploads", PageSize: 2})
+		if files := listAll(it); !reflect.DeepEqual(files, test.files) || it.Err() != nil {
+			t.Errorf("%s: expected %v, got %v: %v", name, test.files, files, it.Err())
+		}
+		if !reflect.DeepEqual(requests, test.requests) {
+			t.Errorf("%s: expected the pages %v, got %v", name, test.requests, requests)
+		}
+		if it.Next() {
+			t.Errorf("%s: expected the iterator to stay exhausted", name)
+		}
+	}
+}
+
+func TestFileIteratorFails(t *testing.T) {
+	useFastBackOff(t)
+	requests := []string{}
+	pages := map[string][]string{"": {"a", "b"}}
+	ac := newTestClient(t, pagedFiles(t, pages, map[string]string{"": "broken"}, &requests))
+
+	it := ac.ListFiles(context.Background(), FileFilter{Dir: "uploads", PageSize: 2})
+	if files := listAll(it); !reflect.DeepEqual(files, []string{"a", "b"}) || !IsServerError(it.Err()) {
+		t.Errorf("expected the files before the failing page and its error, got %v: %v", files, it.Err())
+	}
+	if it.Next() {
+		t.Error("expected the iterator to stay failed")
+	}
+}
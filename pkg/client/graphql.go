@@ -0,0 +1,204 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrorOperationRequired = errors.New("the document holds several operations, select one by name")
+	ErrorOperationNotFound = errors.New("operation not found in the document")
+)
+
+var (
+	operationHeader = regexp.MustCompile(`^(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+	ignoredTokens   = regexp.MustCompile(`(?s)"""(?:\\"""|.)*?"""|"(?:\\.|[^"\\\n])*"|#[^\n]*`)
+)
+
+type Query struct {
+	OperationName *string     `json:"operationName"`
+	Query         string      `json:"query"`
+	Variables     interface{} `json:"variables"`
+}
+
+type QueryResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors QueryErrors            `json:"errors"`
+	// Extensions holds the extra information the API reports on the query, like its cost
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	raw        json.RawMessage
+}
+
+type QueryError struct {
+	Message string `json:"message"`
+	// Path holds the field names and list indexes leading to the failed field
+	Path       []interface{}          `json:"path"`
+	Locations  []QueryErrorLocation   `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type QueryErrorLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// QueryErrors is returned by QueryInto when the API reports errors, possibly alongside partial data
+type QueryErrors []QueryError
+
+// NewQuery returns the query running the named operation of the document, or its only operation when name is empty
+func NewQuery(document string, operationName string, variables interface{}) (Query, error) {
+	q := Query{Query: document, Variables: variables}
+	names, anonymous := Operations(document)
+	if operationName == "" {
+		if len(names)+anonymous > 1 {
+			return q, ErrorOperationRequired
+		}
+		return q, nil
+	}
+	for _, name := range names {
+		if name == operationName {
+			q.OperationName = &operationName
+			return q, nil
+		}
+	}
+	return q, fmt.Errorf("%w: `%s`", ErrorOperationNotFound, operationName)
+}
+
+// Operations returns the names of the operations in the document and the number of anonymous operations
+func Operations(document string) ([]string, int) {
+	document = ignoredTokens.ReplaceAllString(document, " ")
+	names := []string{}
+	anonymous := 0
+
+	// every definition is a header followed by a selection set, so the headers are the text before top level braces
+	depth, start := 0, 0
+	for i, c := range document {
+		switch c {
+		case '{':
+			if depth == 0 {
+				header := strings.TrimSpace(document[start:i])
+				if header == "" {
+					anonymous++
+				} else if match := operationHeader.FindStringSubmatch(header); match != nil {
+					if match[2] == "" {
+						anonymous++
+					} else {
+						names = append(names, match[2])
+					}
+				}
+			}
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				start = i + 1
+			}
+		}
+	}
+
+	return names, anonymous
+}
+
+func (ac *AfostoClient) Query(query string, parameters interface{}) (*QueryResult, error) {
+	return ac.QueryContext(context.Background(), query, parameters)
+}
+
+// QueryContext runs the graphQL query with the parameters
+func (ac *AfostoClient) QueryContext(ctx context.Context, query string, parameters interface{}) (*QueryResult, error) {
+	return ac.QueryOperationContext(ctx, Query{Query: query, Variables: parameters})
+}
+
+// QueryOperationContext runs the query, the errors reported by the API are left in the result
+func (ac *AfostoClient) QueryOperationContext(ctx context.Context, query Query) (*QueryResult, error) {
+	req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s", ac.baseURL, "gql"), jsonPayload(query))
+
+	req.Header.Set("content-type", "application/json")
+
+	var result QueryResult
+	b, _, err := handle(ac.client.Do(req))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	raw := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	result.raw = raw.Data
+
+	return &result, nil
+}
+
+// QueryInto runs the query and decodes its data into out. When the API reports errors, the data it did return is
+// still decoded and the errors are returned as QueryErrors.
+func (ac *AfostoClient) QueryInto(ctx context.Context, query Query, out interface{}) (*QueryResult, error) {
+	result, err := ac.QueryOperationContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := result.Decode(out); err != nil {
+		return result, err
+	}
+	if len(result.Errors) > 0 {
+		return result, result.Errors
+	}
+	return result, nil
+}
+
+// Decode unmarshals the data of the result into out, leaving out untouched when there is no data
+func (r *QueryResult) Decode(out interface{}) error {
+	if len(r.raw) == 0 || string(r.raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(r.raw, out)
+}
+
+// Partial reports whether the API returned data for some fields and errors for others
+func (r *QueryResult) Partial() bool {
+	return len(r.Errors) > 0 && r.Data != nil
+}
+
+// PathString returns the path as a dotted string, like products.2.price
+func (e QueryError) PathString() string {
+	parts := []string{}
+	for _, p := range e.Path {
+		switch v := p.(type) {
+		case float64:
+			parts = append(parts, fmt.Sprintf("%d", int(v)))
+		default:
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func (e QueryError) Error() string {
+	details := []string{}
+	if len(e.Path) > 0 {
+		details = append(details, "at "+e.PathString())
+	}
+	for _, location := range e.Locations {
+		details = append(details, fmt.Sprintf("line %d column %d", location.Line, location.Column))
+	}
+	if len(details) == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, strings.Join(details, ", "))
+}
+
+func (e QueryErrors) Error() string {
+	messages := []string{}
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestOperations(t *testing.T) {
+	tests := map[string]struct {
+		document  string
+		names     []string
+		anonymous int
+	}{
+		"shorthand":      {`{ tenant { id } }`, []string{}, 1},
+		"anonymous":      {`query { tenant { id } }`, []string{}, 1},
+		"named":          {`query Tenant { tenant { id } }`, []string{"Tenant"}, 0},
+		"variables":      {`query Products($first: Int = 10, $filter: Filter = {a: "}"}) { products { id } }`, []string{"Products"}, 0},
+		"several":        {"query A { a }\nmutation B { b { c } }\nsubscription C { c }", []string{"A", "B", "C"}, 0},
+		"fragments":      {`query A { ...F } fragment F on Tenant { id }`, []string{"A"}, 0},
+		"strings":        {`query A { a(text: "{ query B { b } }") } query C { c }`, []string{"A", "C"}, 0},
+		"block strings":  {"query A { a(text: \"\"\"\n} query B {\n\"\"\") }", []string{"A"}, 0},
+		"comments":       {"# query B { b }\nquery A { a } # }", []string{"A"}, 0},
+		"nested":         {`query A { a { b { c { d } } } }`, []string{"A"}, 0},
+		"no whitespace":  {`query A{a}query B{b}`, []string{"A", "B"}, 0},
+		"empty document": {``, []string{}, 0},
+	}
+	for name, test := range tests {
+		names, anonymous := Operations(test.document)
+		if !reflect.DeepEqual(names, test.names) || anonymous != test.anonymous {
+			t.Errorf("%s: expected %v and %d anonymous, got %v and %d", name, test.names, test.anonymous, names, anonymous)
+		}
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	document := `query A { a } query B { b }`
+
+	if _, err := NewQuery(document, "", nil); err != ErrorOperationRequired {
+		t.Errorf("expected %v, got %v", ErrorOperationRequired, err)
+	}
+	if _, err := NewQuery(document, "C", nil); !errors.Is(err, ErrorOperationNotFound) {
+		t.Errorf("expected %v, got %v", ErrorOperationNotFound, err)
+	}
+	q, err := NewQuery(document, "B", map[string]int{"first": 1})
+	if err != nil || q.OperationName == nil || *q.OperationName != "B" || q.Query != document {
+		t.Errorf("expected the operation to be selected, got %+v: %v", q, err)
+	}
+	if q, err := NewQuery(`{ a }`, "", nil); err != nil || q.OperationName != nil {
+		t.Errorf("expected the only operation to run without a name, got %+v: %v", q, err)
+	}
+}
+
+func TestQueryInto(t *testing.T) {
+	var sent Query
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+		_, _ = w.Write([]byte(`{
+			"data": {"products": [{"id": "p1", "price": 10}, {"id": "p2", "price": null}]},
+			"errors": [{"message": "price is hidden", "path": ["products", 1, "price"], "locations": [{"line": 2, "column": 5}]}]
+		}`))
+	}))
+	q, err := NewQuery(`query Products { products { id price } }`, "Products", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := struct {
+		Products []struct {
+			ID    string `json:"id"`
+			Price *int   `json:"price"`
+		} `json:"products"`
+	}{}
+	result, err := ac.QueryInto(context.Background(), q, &out)
+
+	queryErrors := QueryErrors{}
+	if !errors.As(err, &queryErrors) || len(queryErrors) != 1 {
+		t.Fatalf("expected the errors of the query, got %v", err)
+	}
+	if err.Error() != "price is hidden (at products.1.price, line 2 column 5)" {
+		t.Errorf("unexpected message %s", err)
+	}
+	if !result.Partial() || len(out.Products) != 2 || out.Products[0].ID != "p1" || *out.Products[0].Price != 10 {
+		t.Errorf("expected the partial data to be decoded, got %+v", out)
+	}
+	if sent.OperationName == nil || *sent.OperationName != "Products" {
+		t.Errorf("expected the operation name to be sent, got %+v", sent)
+	}
+}
+
+func TestQueryIntoWithoutData(t *testing.T) {
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": null, "errors": [{"message": "syntax error"}]}`))
+	}))
+
+	out := map[string]interface{}{"untouched": true}
+	result, err := ac.QueryInto(context.Background(), Query{Query: "{"}, &out)
+	if err == nil || err.Error() != "syntax error" || result.Partial() || out["untouched"] != true {
+		t.Errorf("expected only the error, got %v and %v", err, out)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"github.com/afosto/cli/pkg/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+const (
+	DefaultUserAgent = "afosto-cli"
+)
+
+// Option configures a client created with New
+type Option func(ac *AfostoClient)
+
+// WithBaseURL sends the requests to the API at baseURL instead of the configured api_url
+func WithBaseURL(baseURL string) Option {
+	return func(ac *AfostoClient) {
+		ac.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sends the requests through the transport of the http client, keeping its timeout and cookie jar
+func WithHTTPClient(client *http.Client) Option {
+	return func(ac *AfostoClient) {
+		ac.httpClient = client
+	}
+}
+
+// WithTokenSource authorizes the requests with the tokens of the source
+func WithTokenSource(source TokenSource) Option {
+	return func(ac *AfostoClient) {
+		ac.source = source
+	}
+}
+
+// WithToken authorizes the requests with a fixed access token
+func WithToken(accessToken string) Option {
+	return WithTokenSource(staticTokenSource(accessToken))
+}
+
+// WithUserAgent sets the User-Agent header of the requests
+func WithUserAgent(userAgent string) Option {
+	return func(ac *AfostoClient) {
+		ac.userAgent = userAgent
+	}
+}
+
+// WithUploadProgress calls progress after each part of a chunked upload
+func WithUploadProgress(progress func(UploadProgress)) Option {
+	return func(ac *AfostoClient) {
+		ac.progress = progress
+	}
+}
+
+// WithLogger writes the messages of the client, like retried requests, to the logger
+func WithLogger(logger logrus.FieldLogger) Option {
+	return func(ac *AfostoClient) {
+		ac.logger = logger
+	}
+}
+
+// New returns a client for the tenant, by default talking to the configured api_url with the shared logger
+func New(tenantID string, options ...Option) *AfostoClient {
+	ac := &AfostoClient{
+		tenantID:  tenantID,
+		baseURL:   strings.TrimRight(settings.ApiURL, "/"),
+		source:    staticTokenSource(""),
+		userAgent: DefaultUserAgent,
+		logger:    logging.Log,
+	}
+	for _, option := range options {
+		option(ac)
+	}
+	ac.c = newSignatureCache()
+	ac.client = ac.newHTTPClient()
+
+	return ac
+}
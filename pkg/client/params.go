@@ -0,0 +1,66 @@
+package client
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Params builds the JSON:API style query string of listings, with filter[field][operator], page[key] and sort
+type Params struct {
+	values url.Values
+}
+
+func NewParams() *Params {
+	return &Params{values: url.Values{}}
+}
+
+// Filter adds filter[field][operator]=value, values of the same filter are all sent
+func (p *Params) Filter(field string, operator string, value string) *Params {
+	p.values.Add("filter["+field+"]["+operator+"]", value)
+	return p
+}
+
+// Page sets page[key]=value, like page[size] and page[after]
+func (p *Params) Page(key string, value string) *Params {
+	p.values.Set("page["+key+"]", value)
+	return p
+}
+
+// Sort orders by the fields, prefix a field with - to sort descending
+func (p *Params) Sort(fields ...string) *Params {
+	if len(fields) > 0 {
+		p.values.Set("sort", strings.Join(fields, ","))
+	}
+	return p
+}
+
+// Encode returns the query string sorted by key. The brackets of keys are left readable, everything else is escaped.
+func (p *Params) Encode() string {
+	keys := make([]string, 0, len(p.values))
+	for key := range p.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		escapedKey := strings.NewReplacer("%5B", "[", "%5D", "]").Replace(url.QueryEscape(key))
+		for _, value := range p.values[key] {
+			pairs = append(pairs, escapedKey+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// URL appends the query string to the url
+func (p *Params) URL(base string) string {
+	query := p.Encode()
+	if query == "" {
+		return base
+	}
+	if strings.Contains(base, "?") {
+		return base + "&" + query
+	}
+	return base + "?" + query
+}
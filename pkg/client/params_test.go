@@ -0,0 +1,66 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParamsEncode(t *testing.T) {
+	params := NewParams().
+		Filter("label", "contains", "summer sale & more").
+		Filter("dir", "eq", "a/b").
+		Filter("dir", "eq", "c=d+e").
+		Page("size", "10").
+		Page("after", "[cursor]").
+		Sort("-created_at", "label")
+
+	expected := "filter[dir][eq]=a%2Fb&filter[dir][eq]=c%3Dd%2Be&filter[label][contains]=summer+sale+%26+more&" +
+		"page[after]=%5Bcursor%5D&page[size]=10&sort=-created_at%2Clabel"
+	if encoded := params.Encode(); encoded != expected {
+		t.Errorf("expected %s, got %s", expected, encoded)
+	}
+}
+
+func TestParamsEscapesKeys(t *testing.T) {
+	params := NewParams().Filter("meta.a&b", "eq", "ü")
+	if encoded := params.Encode(); encoded != "filter[meta.a%26b][eq]=%C3%BC" {
+		t.Errorf("expected the key to be escaped except for its brackets, got %s", encoded)
+	}
+}
+
+func TestParamsURL(t *testing.T) {
+	params := NewParams().Page("size", "10")
+	tests := map[string]string{
+		"https://afosto.app/api/storage/files":       "https://afosto.app/api/storage/files?page[size]=10",
+		"https://afosto.app/api/storage/files?a=b":   "https://afosto.app/api/storage/files?a=b&page[size]=10",
+		"https://afosto.app/api/storage/files?a=b&c": "https://afosto.app/api/storage/files?a=b&c&page[size]=10",
+	}
+	for base, expected := range tests {
+		if u := params.URL(base); u != expected {
+			t.Errorf("expected %s, got %s", expected, u)
+		}
+	}
+	if u := NewParams().URL("https://afosto.app/api"); u != "https://afosto.app/api" {
+		t.Errorf("expected the url without a query, got %s", u)
+	}
+}
+
+func TestFileFilterParams(t *testing.T) {
+	isPublic := false
+	filter := FileFilter{
+		Dir:          "uploads",
+		IsPublic:     &isPublic,
+		Label:        "logo",
+		CreatedAfter: time.Date(2021, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600)),
+		Sort:         []string{"-created_at"},
+	}
+
+	expected := "filter[created_at][gte]=2021-03-01T11%3A00%3A00Z&filter[dir][eq]=uploads&filter[is_public][eq]=false&" +
+		"filter[label][contains]=logo&page[size]=25&sort=-created_at"
+	if encoded := filter.params().Encode(); encoded != expected {
+		t.Errorf("expected %s, got %s", expected, encoded)
+	}
+	if encoded := (FileFilter{}).params().Encode(); encoded != "page[size]=25" {
+		t.Errorf("expected only the page size, got %s", encoded)
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"github.com/cenkalti/backoff/v4"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	maxRetries = 5
+	// maxRetryAfter caps the delay the API can ask for with Retry-After
+	maxRetryAfter = time.Minute * 2
+)
+
+var (
+	// newBackOff returns the delays between the attempts of a request
+	newBackOff = func() backoff.BackOff {
+		b := backoff.NewExponentialBackOff()
+		b.InitialInterval = time.Millisecond * 500
+		b.MaxInterval = time.Second * 30
+		b.MaxElapsedTime = time.Minute * 5
+		return backoff.WithMaxRetries(b, maxRetries)
+	}
+)
+
+// isRetryable reports whether the attempt failed for a transient reason and can safely be repeated.
+// Rate limited requests were not handled by the API, so those are repeated whatever their method.
+func isRetryable(request *http.Request, res *http.Response, err error) bool {
+	if request.Body != nil && request.Body != http.NoBody && request.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return request.Context().Err() == nil && isIdempotent(request)
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(request)
+	}
+	return false
+}
+
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return request.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter returns the delay requested by the Retry-After header, either in seconds or as a date
+func retryAfter(res *http.Response) time.Duration {
+	if res == nil {
+		return 0
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = time.Until(date)
+	}
+	if wait < 0 {
+		return 0
+	}
+	if wait > maxRetryAfter {
+		return maxRetryAfter
+	}
+	return wait
+}
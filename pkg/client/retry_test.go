@@ -0,0 +1,170 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cenkalti/backoff/v4"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failing answers the first requests with the statuses, in order, and the next ones with 200
+func failing(attempts *int32, header http.Header, statuses ...int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(attempts, 1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": "tenant", "name": "Shop"}`))
+	})
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, failing(&attempts, nil, http.StatusServiceUnavailable, http.StatusBadGateway))
+
+	tenant, err := ac.GetTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant.Name != "Shop" || attempts != 3 {
+		t.Errorf("expected the third attempt to succeed, got %+v after %d", tenant, attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	attempts := int32(0)
+	header := http.Header{"Retry-After": {"1"}}
+	ac := newTestClient(t, failing(&attempts, header, http.StatusTooManyRequests))
+
+	start := time.Now()
+	if _, err := ac.GetTenant(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected to wait for the Retry-After of 1s, waited %s", elapsed)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	defaultBackOff := newBackOff
+	newBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), maxRetries)
+	}
+	defer func() {
+		newBackOff = defaultBackOff
+	}()
+
+	attempts := int32(0)
+	statuses := []int{}
+	for i := 0; i <= maxRetries; i++ {
+		statuses = append(statuses, http.StatusTooManyRequests)
+	}
+	ac := newTestClient(t, failing(&attempts, http.Header{"Retry-After": {"0"}}, statuses...))
+	_, err := ac.GetTenant()
+
+	apiError := &APIError{}
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the rate limit error, got %v", err)
+	}
+	if attempts != maxRetries+1 {
+		t.Errorf("expected %d attempts, got %d", maxRetries+1, attempts)
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, failing(&attempts, nil, http.StatusServiceUnavailable))
+
+	if _, err := ac.Query("{ tenant { id } }", nil); !IsServerError(err) || attempts != 1 {
+		t.Errorf("expected the POST to fail without retrying, got %v after %d", err, attempts)
+	}
+
+	// unless it carries an idempotency key
+	attempts = 0
+	req, _ := http.NewRequest(http.MethodPost, ac.baseURL+"/orders", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Idempotency-Key", "key")
+	if _, _, err := handle(ac.client.Do(req)); err != nil || attempts != 2 {
+		t.Errorf("expected the POST to be retried, got %v after %d", err, attempts)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":    0,
+		"3":   3 * time.Second,
+		"-1":  0,
+		"600": maxRetryAfter,
+		time.Now().Add(time.Hour).UTC().Format(http.TimeFormat):  maxRetryAfter,
+		time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat): 0,
+		"soon": 0,
+	}
+	for value, expected := range tests {
+		res := &http.Response{Header: http.Header{"Retry-After": {value}}}
+		if wait := retryAfter(res); wait != expected {
+			t.Errorf("expected %q to wait %s, got %s", value, expected, wait)
+		}
+	}
+}
+
+// rotatingSource issues the expired token until it is refreshed
+type rotatingSource struct {
+	mu        sync.Mutex
+	token     string
+	refreshes int
+}
+
+func (rs *rotatingSource) Token() (string, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.token, nil
+}
+
+func (rs *rotatingSource) Refresh(expired string) (string, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if expired == rs.token {
+		rs.refreshes++
+		rs.token = "fresh"
+	}
+	return rs.token, nil
+}
+
+func TestRefreshOnUnauthorized(t *testing.T) {
+	bodies := []string{}
+	source := &rotatingSource{token: "expired"}
+	ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buffer := &bytes.Buffer{}
+		_, _ = buffer.ReadFrom(r.Body)
+		bodies = append(bodies, buffer.String())
+		if r.Header.Get("authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}), WithTokenSource(source))
+
+	if _, err := ac.Query("{ tenant { id } }", nil); err != nil {
+		t.Fatal(err)
+	}
+	if source.refreshes != 1 || len(bodies) != 2 || bodies[0] != bodies[1] || !strings.Contains(bodies[1], "tenant") {
+		t.Errorf("expected the request to be sent again with its body after one refresh, got %d refreshes and %v", source.refreshes, bodies)
+	}
+}
+
+func TestUnauthorizedWithoutRefresh(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, failing(&attempts, nil, http.StatusUnauthorized))
+
+	if _, err := ac.GetTenant(); !IsUnauthorized(err) || attempts != 1 {
+		t.Errorf("expected the request to fail once, got %v after %d", err, attempts)
+	}
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/data"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+)
+
+const (
+	// UnknownSize is passed as the size of readers whose length is not known up front
+	UnknownSize int64 = -1
+)
+
+var (
+	ErrorUploadResponse = errors.New("got wrong response")
+)
+
+func (ac *AfostoClient) Upload(sourceFilePath string, labelFilename string, signature string) (*data.File, error) {
+	return ac.UploadContext(context.Background(), sourceFilePath, labelFilename, signature)
+}
+
+// UploadContext streams the file to the signature, reopening it when the request has to be sent again.
+// Files larger than ChunkedUploadThreshold are uploaded in parts.
+func (ac *AfostoClient) UploadContext(ctx context.Context, sourceFilePath string, labelFilename string, signature string) (*data.File, error) {
+	info, err := os.Stat(sourceFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > ChunkedUploadThreshold {
+		file, err := os.Open(sourceFilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return ac.uploadChunked(ctx, file, labelFilename, info.Size(), signature)
+	}
+	open := func() (io.ReadCloser, error) {
+		return os.Open(sourceFilePath)
+	}
+	return ac.upload(ctx, open, true, labelFilename, info.Size(), signature)
+}
+
+func (ac *AfostoClient) UploadReader(r io.Reader, labelFilename string, size int64, signature string) (*data.File, error) {
+	return ac.UploadReaderContext(context.Background(), r, labelFilename, size, signature)
+}
+
+// UploadReaderContext streams the contents of the reader to the signature. Pass UnknownSize when the length of the
+// reader is not known, the upload is then sent chunked. Readers larger than ChunkedUploadThreshold that can be read
+// at an offset are uploaded in parts. Only readers that can seek, or be read at an offset, are sent again after
+// transient failures.
+func (ac *AfostoClient) UploadReaderContext(ctx context.Context, r io.Reader, labelFilename string, size int64, signature string) (*data.File, error) {
+	readerAt, isReaderAt := r.(io.ReaderAt)
+	if isReaderAt && size > ChunkedUploadThreshold {
+		return ac.uploadChunked(ctx, readerAt, labelFilename, size, signature)
+	}
+
+	opened := false
+	open := func() (io.ReadCloser, error) {
+		if opened {
+			return nil, errors.New("the reader can only be uploaded once")
+		}
+		opened = true
+		return ioutil.NopCloser(r), nil
+	}
+	rewindable := false
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		open = func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(r), nil
+		}
+		rewindable = true
+	} else if isReaderAt && size != UnknownSize {
+		open = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(io.NewSectionReader(readerAt, 0, size)), nil
+		}
+		rewindable = true
+	}
+	return ac.upload(ctx, open, rewindable, labelFilename, size, signature)
+}
+
+// upload sends the form, the request is only sent again after transient failures when open can be called again
+func (ac *AfostoClient) upload(ctx context.Context, open func() (io.ReadCloser, error), rewindable bool, labelFilename string, size int64, signature string) (*data.File, error) {
+	body, err := newMultipartBody(open, labelFilename, size)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := body.reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/%s", ac.baseURL, "storage/files/upload/"+signature), reader)
+	req.Header.Set("content-type", body.contentType)
+	req.ContentLength = body.length()
+	if rewindable {
+		req.GetBody = body.reader
+	}
+
+	type response struct {
+		Data []data.File `json:"data"`
+	}
+
+	var fileResponse response
+	b, _, err := handle(ac.client.Do(req))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, &fileResponse); err != nil {
+		return nil, err
+	}
+
+	if len(fileResponse.Data) > 0 {
+		return &fileResponse.Data[0], nil
+	}
+
+	return nil, ErrorUploadResponse
+}
+
+// multipartBody writes the file into a multipart form while it is being sent, instead of buffering it
+type multipartBody struct {
+	open        func() (io.ReadCloser, error)
+	field       string
+	filename    string
+	boundary    string
+	contentType string
+	size        int64
+	overhead    int64
+}
+
+func newMultipartBody(open func() (io.ReadCloser, error), filename string, size int64) (*multipartBody, error) {
+	// the form around the file is rendered once without contents, to know the length of the body up front
+	envelope := &countingWriter{}
+	writer := multipart.NewWriter(envelope)
+	if _, err := writer.CreateFormFile("file", filename); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return &multipartBody{
+		open:        open,
+		field:       "file",
+		filename:    filename,
+		boundary:    writer.Boundary(),
+		contentType: writer.FormDataContentType(),
+		size:        size,
+		overhead:    envelope.n,
+	}, nil
+}
+
+// length returns the content length of the body, or -1 when the size of the file is unknown
+func (mb *multipartBody) length() int64 {
+	if mb.size == UnknownSize {
+		return -1
+	}
+	return mb.overhead + mb.size
+}
+
+// reader opens the file and returns the form, written through a pipe as the transport reads it
+func (mb *multipartBody) reader() (io.ReadCloser, error) {
+	file, err := mb.open()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer file.Close()
+		writer := multipart.NewWriter(pw)
+		if err := writer.SetBoundary(mb.boundary); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		part, err := writer.CreateFormFile(mb.field, mb.filename)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(part, file); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(writer.Close())
+	}()
+
+	return pr, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
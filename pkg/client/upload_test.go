@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// rateLimitedOnce answers the first upload with 429 and accepts the next ones, if their file is complete
+func rateLimitedOnce(t *testing.T, contents string, attempts *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("could not read the form: %v", err)
+			return
+		}
+		b, _ := ioutil.ReadAll(file)
+		if string(b) != contents {
+			t.Errorf("expected %q to be uploaded, got %q", contents, b)
+		}
+		if atomic.AddInt32(attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": [{"filename": "a.txt"}]}`))
+	})
+}
+
+func TestUploadFileIsRetried(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := ioutil.WriteFile(path, []byte("contents"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	attempts := int32(0)
+	ac := newTestClient(t, rateLimitedOnce(t, "contents", &attempts))
+
+	file, err := ac.UploadContext(context.Background(), path, "a.txt", "signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Filename != "a.txt" || attempts != 2 {
+		t.Errorf("expected the file to be reopened for the second attempt, got %+v after %d", file, attempts)
+	}
+}
+
+func TestUploadContentLength(t *testing.T) {
+	tests := map[string]int64{
+		"known size":   int64(len("contents")),
+		"unknown size": UnknownSize,
+	}
+	for name, size := range tests {
+		var length int64
+		var chunked bool
+		ac := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			length = r.ContentLength
+			chunked = len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked"
+			b, _ := ioutil.ReadAll(r.Body)
+			if size != UnknownSize && int64(len(b)) != length {
+				t.Errorf("%s: expected a body of %d bytes, got %d", name, length, len(b))
+			}
+			_, _ = w.Write([]byte(`{"data": [{"filename": "a.txt"}]}`))
+		}))
+
+		if _, err := ac.UploadReaderContext(context.Background(), strings.NewReader("contents"), "a.txt", size, "signature"); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if size == UnknownSize && (!chunked || length != -1) {
+			t.Errorf("%s: expected a chunked upload, got a length of %d", name, length)
+		}
+		if size != UnknownSize && (chunked || length <= size) {
+			t.Errorf("%s: expected the length of the form, got %d", name, length)
+		}
+	}
+}
+
+func TestUploadMissingFile(t *testing.T) {
+	ac := newTestClient(t, http.NotFoundHandler())
+	if _, err := ac.Upload(filepath.Join(t.TempDir(), "missing.txt"), "missing.txt", "signature"); !os.IsNotExist(err) {
+		t.Errorf("expected the missing file to be reported, got %v", err)
+	}
+}
+
+func TestUploadReaderOnceIsNotRetried(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, rateLimitedOnce(t, "contents", &attempts))
+
+	// hide the seeker and reader at of the strings reader
+	r := struct{ io.Reader }{strings.NewReader("contents")}
+	_, err := ac.UploadReaderContext(context.Background(), r, "a.txt", int64(len("contents")), "signature")
+
+	apiError := &APIError{}
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected the rate limit error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestUploadSeekerIsRetried(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, rateLimitedOnce(t, "contents", &attempts))
+
+	r := bytes.NewReader([]byte("..contents"))
+	if _, err := r.Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	file, err := ac.UploadReaderContext(context.Background(), r, "a.txt", int64(len("contents")), "signature")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Filename != "a.txt" || attempts != 2 {
+		t.Errorf("expected the upload to succeed on the second attempt, got %+v after %d", file, attempts)
+	}
+}
+
+func TestUploadReaderAtIsRetried(t *testing.T) {
+	attempts := int32(0)
+	ac := newTestClient(t, rateLimitedOnce(t, "contents", &attempts))
+
+	// hide the seeker of the strings reader
+	r := struct {
+		io.Reader
+		io.ReaderAt
+	}{strings.NewReader("contents"), strings.NewReader("contents")}
+	if _, err := ac.UploadReaderContext(context.Background(), r, "a.txt", int64(len("contents")), "signature"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected two attempts, got %d", attempts)
+	}
+}
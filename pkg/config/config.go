@@ -0,0 +1,183 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	ConfigFile = "config.yml"
+	EnvPrefix  = "AFOSTO_"
+)
+
+var (
+	ErrorUnknownKey = errors.New("unknown configuration key")
+)
+
+// Config holds the endpoints and client settings the cli talks to
+type Config struct {
+	ApiURL                 string `yaml:"api_url,omitempty"`
+	AuthorizationURL       string `yaml:"authorization_url,omitempty"`
+	DeviceAuthorizationURL string `yaml:"device_authorization_url,omitempty"`
+	TokenURL               string `yaml:"token_url,omitempty"`
+	JWKSURL                string `yaml:"jwks_url,omitempty"`
+	Issuer                 string `yaml:"issuer,omitempty"`
+	ClientID               string `yaml:"client_id,omitempty"`
+	RedirectURL            string `yaml:"redirect_url,omitempty"`
+}
+
+// Key describes a configuration value and where it can be overridden
+type Key struct {
+	Name        string
+	Description string
+	IsURL       bool
+	field       func(c *Config) *string
+}
+
+var Keys = []Key{
+	{Name: "api_url", Description: "Base URL of the API", IsURL: true, field: func(c *Config) *string { return &c.ApiURL }},
+	{Name: "authorization_url", Description: "OAuth authorization endpoint", IsURL: true, field: func(c *Config) *string { return &c.AuthorizationURL }},
+	{Name: "device_authorization_url", Description: "OAuth device authorization endpoint", IsURL: true, field: func(c *Config) *string { return &c.DeviceAuthorizationURL }},
+	{Name: "token_url", Description: "OAuth token endpoint", IsURL: true, field: func(c *Config) *string { return &c.TokenURL }},
+	{Name: "jwks_url", Description: "URL of the keys tokens are signed with", IsURL: true, field: func(c *Config) *string { return &c.JWKSURL }},
+	{Name: "issuer", Description: "Expected issuer of tokens", field: func(c *Config) *string { return &c.Issuer }},
+	{Name: "client_id", Description: "OAuth client ID of the cli", field: func(c *Config) *string { return &c.ClientID }},
+	{Name: "redirect_url", Description: "Local URL the browser redirects to after logging in", IsURL: true, field: func(c *Config) *string { return &c.RedirectURL }},
+}
+
+// Default returns the configuration for the production environment
+func Default() *Config {
+	return &Config{
+		ApiURL:                 "https://afosto.app/api",
+		AuthorizationURL:       "https://afosto.app/auth/authorize",
+		DeviceAuthorizationURL: "https://afosto.app/auth/device",
+		TokenURL:               "https://afosto.app/auth/token",
+		JWKSURL:                "https://afosto.app/auth/.well-known/jwks.json",
+		Issuer:                 "https://afosto.app/auth",
+		ClientID:               "51403354ded11942d7195c66b9e81f71b74f56cd8adc539277823e179da8",
+		RedirectURL:            "http://localhost:8888/return",
+	}
+}
+
+// Dir returns the directory holding the configuration of the cli (~/.config/afosto)
+func Dir() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "afosto"), nil
+}
+
+// DefaultPath returns the path of the configuration file
+func DefaultPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ConfigFile), nil
+}
+
+// Load layers the defaults, the configuration file, AFOSTO_* environment variables and the overrides, in that order
+func Load(path string, overrides map[string]string) (*Config, error) {
+	cfg := Default()
+
+	file, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range Keys {
+		if value := *key.field(file); value != "" {
+			*key.field(cfg) = value
+		}
+		if value := os.Getenv(key.Env()); value != "" {
+			*key.field(cfg) = value
+		}
+		if value, ok := overrides[key.Name]; ok && value != "" {
+			*key.field(cfg) = value
+		}
+	}
+
+	return cfg, nil
+}
+
+// ReadFile returns the values set in the configuration file, an absent file is empty
+func ReadFile(path string) (*Config, error) {
+	cfg := &Config{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// WriteFile stores the values in the configuration file
+func WriteFile(path string, cfg *Config) error {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// GetKey returns the description of the key
+func GetKey(name string) (Key, error) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w `%s`, use one of: %s", ErrorUnknownKey, name, strings.Join(KeyNames(), ", "))
+}
+
+// KeyNames returns the sorted names of all keys
+func KeyNames() []string {
+	names := []string{}
+	for _, key := range Keys {
+		names = append(names, key.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Env returns the environment variable overriding the key
+func (k Key) Env() string {
+	return EnvPrefix + strings.ToUpper(k.Name)
+}
+
+// Flag returns the name of the flag overriding the key
+func (k Key) Flag() string {
+	return strings.ReplaceAll(k.Name, "_", "-")
+}
+
+func (k Key) Get(cfg *Config) string {
+	return *k.field(cfg)
+}
+
+func (k Key) Set(cfg *Config, value string) error {
+	if k.IsURL && value != "" {
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("`%s` is not a valid URL for %s", value, k.Name)
+		}
+	}
+	*k.field(cfg) = value
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFile)
+	if err := WriteFile(path, &Config{ApiURL: "https://file.example/api", TokenURL: "https://file.example/token"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AFOSTO_TOKEN_URL", "https://env.example/token")
+	t.Setenv("AFOSTO_CLIENT_ID", "env")
+
+	cfg, err := Load(path, map[string]string{"client_id": "flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ApiURL != "https://file.example/api" {
+		t.Errorf("expected the file to override the default, got %s", cfg.ApiURL)
+	}
+	if cfg.TokenURL != "https://env.example/token" {
+		t.Errorf("expected the environment to override the file, got %s", cfg.TokenURL)
+	}
+	if cfg.ClientID != "flag" {
+		t.Errorf("expected the flag to override the environment, got %s", cfg.ClientID)
+	}
+	if cfg.Issuer != Default().Issuer {
+		t.Errorf("expected the default issuer, got %s", cfg.Issuer)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), ConfigFile), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *cfg != *Default() {
+		t.Errorf("expected the defaults, got %+v", cfg)
+	}
+}
+
+func TestKeySet(t *testing.T) {
+	key, err := GetKey("api_url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := key.Set(cfg, "not a url"); err == nil {
+		t.Error("expected an invalid URL to be rejected")
+	}
+	if err := key.Set(cfg, "https://example.com/api"); err != nil || key.Get(cfg) != "https://example.com/api" {
+		t.Errorf("expected the URL to be set, got %s %v", key.Get(cfg), err)
+	}
+
+	if _, err := GetKey("api"); !errors.Is(err, ErrorUnknownKey) {
+		t.Errorf("expected %v, got %v", ErrorUnknownKey, err)
+	}
+}
@@ -5,12 +5,15 @@ import (
 )
 
 type User struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Email       string `json:"email"`
-	TenantID    string `json:"tenant_id"`
-	TenantName  string `json:"tenant_name"`
-	accessToken string
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Email        string   `json:"email"`
+	TenantID     string   `json:"tenant_id"`
+	TenantName   string   `json:"tenant_name"`
+	Scopes       []string `json:"scopes,omitempty"`
+	DeniedScopes []string `json:"denied_scopes,omitempty"`
+	accessToken  string
+	refreshToken string
 }
 
 func (u *User) SetAccessToken(token string) {
@@ -21,21 +24,32 @@ func (u *User) GetAccessToken() string {
 	return u.accessToken
 }
 
+func (u *User) SetRefreshToken(token string) {
+	u.refreshToken = token
+}
+
+func (u *User) GetRefreshToken() string {
+	return u.refreshToken
+}
+
 func (u *User) MarshalJSON() ([]byte, error) {
 	type Alias User
 	return json.Marshal(&struct {
-		AccessToken string `json:"token"`
+		AccessToken  string `json:"token"`
+		RefreshToken string `json:"refresh_token,omitempty"`
 		*Alias
 	}{
-		AccessToken: u.GetAccessToken(),
-		Alias:       (*Alias)(u),
+		AccessToken:  u.GetAccessToken(),
+		RefreshToken: u.GetRefreshToken(),
+		Alias:        (*Alias)(u),
 	})
 }
 
 func (u *User) UnmarshalJSON(data []byte) error {
 	type Alias User
 	aux := &struct {
-		AccessToken string `json:"token"`
+		AccessToken  string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 		*Alias
 	}{
 		Alias: (*Alias)(u),
@@ -44,5 +58,6 @@ func (u *User) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	u.accessToken = aux.AccessToken
+	u.refreshToken = aux.RefreshToken
 	return nil
 }
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"github.com/afosto/cli/pkg/data"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// QueryFile is a query file referenced by the routes of a template definition
+type QueryFile struct {
+	// Path is the path of the file relative to the definition, as written in the definition
+	Path string
+	// Resolved is the path of the file relative to the working directory
+	Resolved string
+	Routes   []string
+}
+
+// LoadDefinition reads the template definition in the afosto.config.yml at path
+func LoadDefinition(path string) (*data.TemplateDefinition, error) {
+	configData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	definition := data.TemplateDefinition{}
+	if err := yaml.Unmarshal(configData, &definition); err != nil {
+		return nil, err
+	}
+	return &definition, nil
+}
+
+// QueryFiles returns the query files referenced by the routes of the definition at path, in order of appearance
+func QueryFiles(path string) ([]QueryFile, error) {
+	definition, err := LoadDefinition(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []QueryFile{}
+	index := map[string]int{}
+	for _, entry := range definition.Config {
+		for _, route := range entry.Routes {
+			if route.QueryPath == nil {
+				continue
+			}
+			i, ok := index[*route.QueryPath]
+			if !ok {
+				i = len(files)
+				index[*route.QueryPath] = i
+				files = append(files, QueryFile{
+					Path:     *route.QueryPath,
+					Resolved: filepath.Join(filepath.Dir(path), *route.QueryPath),
+				})
+			}
+			files[i].Routes = append(files[i].Routes, entry.Category+"/"+route.Path)
+		}
+	}
+	return files, nil
+}
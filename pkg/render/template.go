@@ -11,13 +11,12 @@ import (
 	"github.com/flosch/pongo2/v4"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
-	"gopkg.in/yaml.v2"
 	"html/template"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 const (
@@ -41,7 +40,7 @@ type developmentServer struct {
 	definition     *data.TemplateDefinition
 }
 
-func GetDevelopmentServer(hostname string, port int, path string, user *data.User) (*developmentServer, error) {
+func GetDevelopmentServer(hostname string, port int, path string, ac *client.AfostoClient) (*developmentServer, error) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, ErrorConfigFileNotFound
 	}
@@ -60,34 +59,37 @@ func GetDevelopmentServer(hostname string, port int, path string, user *data.Use
 		port:           port,
 		configFilePath: path,
 		pongo:          pongo2.NewSet("templates", loader),
-		client:         client.GetClient(user.TenantID, user.GetAccessToken()),
+		client:         ac,
 	}
 
-	return devServer, nil
-}
-
-func (ds *developmentServer) Start() {
 	r := mux.NewRouter()
-	r.HandleFunc("/index", ds.index).Methods("GET")
+	r.HandleFunc("/index", devServer.index).Methods("GET")
 
-	r.HandleFunc("/assets/{file}", ds.serveAsset).Methods("GET")
-	r.Handle("/assets/{file}", http.StripPrefix("/assets/", http.FileServer(http.Dir(filepath.Dir(ds.configFilePath)+"/dist/"))))
-	r.HandleFunc("/render/{category}/{path}/{id}", ds.render).Methods("GET")
-	r.HandleFunc("/render/{category}/{path}", ds.render).Methods("GET")
+	r.HandleFunc("/assets/{file}", devServer.serveAsset).Methods("GET")
+	r.Handle("/assets/{file}", http.StripPrefix("/assets/", http.FileServer(http.Dir(filepath.Dir(path)+"/dist/"))))
+	r.HandleFunc("/render/{category}/{path}/{id}", devServer.render).Methods("GET")
+	r.HandleFunc("/render/{category}/{path}", devServer.render).Methods("GET")
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", ds.hostname, ds.port),
+	// the server is built up front, so Stop can shut it down whether Start has run yet or not
+	devServer.server = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", hostname, port),
 		Handler: r,
 	}
-	ds.server = server
+
+	return devServer, nil
+}
+
+func (ds *developmentServer) Start() {
 	err := ds.server.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		logging.Log.Fatal(err)
 	}
 }
 
 func (ds *developmentServer) Stop() {
-	_ = ds.server.Shutdown(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_ = ds.server.Shutdown(ctx)
 }
 
 func (ds *developmentServer) render(w http.ResponseWriter, req *http.Request) {
@@ -113,8 +115,13 @@ func (ds *developmentServer) render(w http.ResponseWriter, req *http.Request) {
 
 		if entry.ResolvedQuery != nil {
 			logging.Log.WithField("input", *entry.QueryPath).Debug("running graphQL query")
-			result, err = ds.client.Query(*entry.ResolvedQuery, params)
-			if err != nil {
+			result, err = ds.client.QueryContext(req.Context(), *entry.ResolvedQuery, params)
+			if client.IsUnauthorized(err) || client.IsForbidden(err) {
+				logging.Log.Error(err)
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte("the API rejected the credentials, log in again with `afosto auth login`"))
+				return
+			} else if err != nil {
 				logging.Log.Error(err)
 				return
 			}
@@ -122,7 +129,7 @@ func (ds *developmentServer) render(w http.ResponseWriter, req *http.Request) {
 				logging.Log.Error("got errors running the graphQL query")
 				for k := range result.Errors {
 					if len(result.Errors[k].Path) > 0 {
-						logging.Log.WithField("path", strings.Join(result.Errors[k].Path, ".")).Error(result.Errors[k].Message)
+						logging.Log.WithField("path", result.Errors[k].PathString()).Error(result.Errors[k].Message)
 					} else {
 						logging.Log.Error(result.Errors[k].Message)
 					}
@@ -151,7 +158,11 @@ func (ds *developmentServer) render(w http.ResponseWriter, req *http.Request) {
 		}
 
 		if v, ok := req.URL.Query()["dump"]; ok {
-			logging.Log.WithField("input", *entry.QueryPath).Debug("dumping data for query")
+			if entry.QueryPath != nil {
+				logging.Log.WithField("input", *entry.QueryPath).Debug("dumping data for query")
+			} else {
+				logging.Log.WithField("input", entry.TemplatePath).Debug("dumping data without query")
+			}
 			if v[0] == "1" {
 				jsonData, err := json.Marshal(templateData)
 				if err != nil {
@@ -248,12 +259,7 @@ func (ds *developmentServer) serveAsset(w http.ResponseWriter, req *http.Request
 }
 
 func (ds *developmentServer) reload() error {
-	configData, err := ioutil.ReadFile(ds.configFilePath)
-	if err != nil {
-		return err
-	}
-	definition := data.TemplateDefinition{}
-	err = yaml.Unmarshal(configData, &definition)
+	definition, err := LoadDefinition(ds.configFilePath)
 	if err != nil {
 		return err
 	}
@@ -280,6 +286,6 @@ func (ds *developmentServer) reload() error {
 	}
 
 	ds.entries = entries
-	ds.definition = &definition
+	ds.definition = definition
 	return nil
 }
@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	goScalars = map[string]string{
+		"ID":      "string",
+		"String":  "string",
+		"Int":     "int",
+		"Float":   "float64",
+		"Boolean": "bool",
+	}
+	initialisms = map[string]bool{
+		"API": true, "HTML": true, "HTTP": true, "ID": true, "JSON": true, "SKU": true, "URL": true, "UUID": true,
+	}
+)
+
+// GoGenerator writes Go types for the data and variables of operations. Types are named after the operations and
+// numbered within their file only, so the names of a file never change with other files. As the files share a
+// package, generating a name another file already has fails.
+type GoGenerator struct {
+	Package string
+	// sources holds the query file each type name was generated from
+	sources map[string]string
+}
+
+type goFile struct {
+	buffer   *bytes.Buffer
+	names    map[string]bool
+	usesJSON bool
+}
+
+func NewGoGenerator(pkg string) *GoGenerator {
+	return &GoGenerator{Package: pkg, sources: map[string]string{}}
+}
+
+// Generate returns the formatted Go source holding the types of the operations in the source file
+func (g *GoGenerator) Generate(source string, operations []*Operation) ([]byte, error) {
+	file := &goFile{buffer: &bytes.Buffer{}, names: map[string]bool{}}
+	for _, op := range operations {
+		name := exportName(op.Name)
+		variablesName := file.typeName(name + "Variables")
+		dataName := file.typeName(name + "Data")
+		fmt.Fprintf(file.buffer, "\n// %s holds the variables of %s\n", variablesName, op.Name)
+		g.writeVariables(file, variablesName, op)
+		fmt.Fprintf(file.buffer, "\n// %s holds the data %s returns\n", dataName, op.Name)
+		g.writeStruct(file, op.schema, dataName, op.root(), []ast.SelectionSet{op.definition.SelectionSet})
+	}
+
+	names := make([]string, 0, len(file.names))
+	for name := range file.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if other, ok := g.sources[name]; ok {
+			return nil, fmt.Errorf("type %s of %s is also generated from %s, rename the operation in one of them", name, source, other)
+		}
+	}
+	for _, name := range names {
+		g.sources[name] = source
+	}
+
+	header := &bytes.Buffer{}
+	fmt.Fprintf(header, "// Code generated by afosto gql generate from %s. DO NOT EDIT.\n\npackage %s\n", source, g.Package)
+	if file.usesJSON {
+		header.WriteString("\nimport \"encoding/json\"\n")
+	}
+	header.Write(file.buffer.Bytes())
+
+	return format.Source(header.Bytes())
+}
+
+func (g *GoGenerator) writeVariables(file *goFile, name string, op *Operation) {
+	fields := map[string]bool{}
+	fmt.Fprintf(file.buffer, "type %s struct {\n", name)
+	for _, variable := range op.definition.VariableDefinitions {
+		tag := variable.Variable
+		if !variable.Type.NonNull || variable.DefaultValue != nil {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(file.buffer, "%s %s `json:%s`\n", fieldName(fields, variable.Variable), g.inputType(file, op.schema, variable.Type), strconv.Quote(tag))
+	}
+	file.buffer.WriteString("}\n")
+}
+
+// writeStruct writes the struct of the fields selected on the parent, followed by the structs of nested selections
+func (g *GoGenerator) writeStruct(file *goFile, s *ast.Schema, name string, parent *ast.Definition, sets []ast.SelectionSet) {
+	file.names[name] = true
+	nested := []func(){}
+	fields := map[string]bool{}
+
+	fmt.Fprintf(file.buffer, "type %s struct {\n", name)
+	for _, field := range selections(parent, sets) {
+		if field.definition == nil {
+			continue
+		}
+		goName := fieldName(fields, field.key)
+		fieldType := field.definition.Type
+		named := typeNameOf(fieldType)
+		definition := s.Types[named]
+		var goType string
+		if isComposite(definition) {
+			nestedName := file.typeName(name + goName)
+			fieldSets := field.sets
+			nested = append(nested, func() {
+				file.buffer.WriteString("\n")
+				g.writeStruct(file, s, nestedName, definition, fieldSets)
+			})
+			goType = wrapType(fieldType, nestedName, field.optional)
+		} else {
+			goType = wrapType(fieldType, g.scalarType(file, definition, named), field.optional)
+		}
+
+		tag := field.key
+		if field.optional {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(file.buffer, "%s %s `json:%s`\n", goName, goType, strconv.Quote(tag))
+	}
+	file.buffer.WriteString("}\n")
+
+	for _, write := range nested {
+		write()
+	}
+}
+
+// inputType maps variables onto Go types, input objects are left as maps
+func (g *GoGenerator) inputType(file *goFile, s *ast.Schema, t *ast.Type) string {
+	named := typeNameOf(t)
+	definition := s.Types[named]
+	goType := "map[string]interface{}"
+	if definition == nil || definition.Kind != ast.InputObject {
+		goType = g.scalarType(file, definition, named)
+	}
+	return wrapType(t, goType, false)
+}
+
+func (g *GoGenerator) scalarType(file *goFile, definition *ast.Definition, named string) string {
+	if goType, ok := goScalars[named]; ok {
+		return goType
+	}
+	if definition != nil && definition.Kind == ast.Enum {
+		return "string"
+	}
+	// custom scalars are left for the caller to decode
+	file.usesJSON = true
+	return "json.RawMessage"
+}
+
+// typeName returns name, or name with a number when the file has it already
+func (file *goFile) typeName(name string) string {
+	unique := name
+	for i := 2; file.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	file.names[unique] = true
+	return unique
+}
+
+// fieldName returns the exported name of the key, or that name with a number when the struct already has it, like
+// for the aliases created_at and createdAt
+func fieldName(used map[string]bool, key string) string {
+	name := exportName(key)
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// wrapType adds the slices and pointers of the GraphQL type around the Go type of its named type
+func wrapType(t *ast.Type, goType string, optional bool) string {
+	if t.Elem != nil {
+		return "[]" + wrapType(t.Elem, goType, false)
+	}
+	if (!t.NonNull || optional) && !strings.HasPrefix(goType, "map[") && goType != "json.RawMessage" {
+		return "*" + goType
+	}
+	return goType
+}
+
+func isComposite(definition *ast.Definition) bool {
+	return definition != nil && (definition.Kind == ast.Object || definition.Kind == ast.Interface || definition.Kind == ast.Union)
+}
+
+// typeNameOf returns the named type within the lists of the type
+func typeNameOf(t *ast.Type) string {
+	for t.Elem != nil {
+		t = t.Elem
+	}
+	return t.NamedType
+}
+
+// exportName turns a GraphQL name like created_at or __typename into an exported Go name like CreatedAt
+func exportName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	})
+	sb := &strings.Builder{}
+	for _, part := range parts {
+		if initialisms[strings.ToUpper(part)] {
+			sb.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		sb.WriteString(string(runes))
+	}
+	if sb.Len() == 0 {
+		return "Field"
+	}
+	return sb.String()
+}
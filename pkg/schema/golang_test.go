@@ -0,0 +1,279 @@
+package schema
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSchema = `
+scalar Money
+
+enum Status {
+  ACTIVE
+  HIDDEN
+}
+
+interface Node {
+  id: ID!
+}
+
+type Product implements Node {
+  id: ID!
+  name: String!
+  price: Money
+  status: Status
+  tags: [String!]
+  variants: [Variant!]!
+}
+
+type Variant {
+  sku: String
+  stock: Int!
+}
+
+type Category implements Node {
+  id: ID!
+  name: String!
+}
+
+input ProductFilter {
+  status: Status
+}
+
+type Query {
+  products(filter: ProductFilter, first: Int = 10): [Product!]!
+  node(id: ID!): Node
+}
+`
+
+const testQuery = `
+query Products($filter: ProductFilter, $first: Int) {
+  products(filter: $filter, first: $first) {
+    id
+    name
+    price
+    status
+    tags
+    variants { sku stock }
+  }
+}
+
+query Node($id: ID!) {
+  node(id: $id) {
+    __typename
+    id
+    ... on Product { name }
+    ...CategoryName
+  }
+}
+
+fragment CategoryName on Category {
+  name
+}
+`
+
+// loadTestOperations writes the query to a file named like the name and loads its operations
+func loadTestOperations(t *testing.T, s string, name string, query string) []*Operation {
+	t.Helper()
+	parsed, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(query), 0600); err != nil {
+		t.Fatal(err)
+	}
+	operations, problems, err := LoadOperations(parsed, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	return operations
+}
+
+// compile type checks the generated sources as one package
+func compile(t *testing.T, sources ...[]byte) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	files := []*ast.File{}
+	for i, source := range sources {
+		file, err := parser.ParseFile(fset, fmt.Sprintf("file%d.go", i), source, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("queries", fset, files, nil)
+	if err != nil {
+		t.Fatalf("the generated code does not compile: %v\n%s", err, sources)
+	}
+	return pkg
+}
+
+// structFields lists the fields of the struct as name, type and tag
+func structFields(t *testing.T, pkg *types.Package, name string) string {
+	t.Helper()
+	object := pkg.Scope().Lookup(name)
+	if object == nil {
+		t.Fatalf("missing type %s", name)
+	}
+	qualifier := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	s := object.Type().Underlying().(*types.Struct)
+	fields := []string{}
+	for i := 0; i < s.NumFields(); i++ {
+		fields = append(fields, fmt.Sprintf("%s %s %s", s.Field(i).Name(), types.TypeString(s.Field(i).Type(), qualifier), s.Tag(i)))
+	}
+	return strings.Join(fields, "\n")
+}
+
+func TestGenerateCompiles(t *testing.T) {
+	operations := loadTestOperations(t, testSchema, "products.graphql", testQuery)
+
+	source, err := NewGoGenerator("queries").Generate("products.graphql", operations)
+	if err != nil {
+		t.Fatalf("the generated code does not format: %v", err)
+	}
+	if !strings.HasPrefix(string(source), "// Code generated by afosto gql generate from products.graphql. DO NOT EDIT.\n\npackage queries\n") {
+		t.Errorf("unexpected header in\n%s", source)
+	}
+	pkg := compile(t, source)
+
+	expected := map[string]string{
+		"ProductsVariables": "Filter map[string]interface{} json:\"filter,omitempty\"\n" +
+			"First *int json:\"first,omitempty\"",
+		"ProductsData": "Products []ProductsDataProducts json:\"products\"",
+		"ProductsDataProducts": "ID string json:\"id\"\n" +
+			"Name string json:\"name\"\n" +
+			"Price json.RawMessage json:\"price\"\n" +
+			"Status *string json:\"status\"\n" +
+			"Tags []string json:\"tags\"\n" +
+			"Variants []ProductsDataProductsVariants json:\"variants\"",
+		"ProductsDataProductsVariants": "SKU *string json:\"sku\"\n" +
+			"Stock int json:\"stock\"",
+		"NodeVariables": "ID string json:\"id\"",
+		"NodeData":      "Node *NodeDataNode json:\"node\"",
+		"NodeDataNode": "Typename string json:\"__typename\"\n" +
+			"ID string json:\"id\"\n" +
+			"Name *string json:\"name,omitempty\"",
+	}
+	for name, fields := range expected {
+		if actual := structFields(t, pkg, name); actual != fields {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, fields, actual)
+		}
+	}
+}
+
+func TestExportName(t *testing.T) {
+	tests := map[string]string{
+		"created_at": "CreatedAt",
+		"__typename": "Typename",
+		"productId":  "ProductId",
+		"sku":        "SKU",
+		"api_url":    "APIURL",
+		"_":          "Field",
+	}
+	for name, expected := range tests {
+		if actual := exportName(name); actual != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, actual)
+		}
+	}
+}
+
+func TestGenerateUniqueNames(t *testing.T) {
+	s := `
+type Product {
+  id: ID!
+  created_at: String
+  createdAt: String
+}
+
+type Query {
+  product(id: ID!, created_at: String, createdAt: String): Product
+  variables: Product
+}
+`
+	query := `
+query Order($id: ID!, $created_at: String, $createdAt: String) {
+  product(id: $id, created_at: $created_at, createdAt: $createdAt) {
+    id
+    created_at
+    createdAt
+    first: created_at
+    First: createdAt
+  }
+  variables { id }
+}
+
+query OrderData {
+  product(id: "1") { id }
+}
+`
+	operations := loadTestOperations(t, s, "order.graphql", query)
+	source, err := NewGoGenerator("queries").Generate("order.graphql", operations)
+	if err != nil {
+		t.Fatalf("the generated code does not format: %v", err)
+	}
+	pkg := compile(t, source)
+
+	expected := "ID string json:\"id\"\n" +
+		"CreatedAt *string json:\"created_at\"\n" +
+		"CreatedAt2 *string json:\"createdAt\"\n" +
+		"First *string json:\"first\"\n" +
+		"First2 *string json:\"First\""
+	if fields := structFields(t, pkg, "OrderDataProduct"); fields != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, fields)
+	}
+	if fields := structFields(t, pkg, "OrderVariables"); fields != "ID string json:\"id\"\nCreatedAt *string json:\"created_at,omitempty\"\nCreatedAt2 *string json:\"createdAt,omitempty\"" {
+		t.Errorf("expected unique variables, got\n%s", fields)
+	}
+	for _, name := range []string{"OrderData", "OrderDataVariables", "OrderDataVariables2", "OrderDataData"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("missing type %s in\n%s", name, source)
+		}
+	}
+}
+
+func TestGenerateNamesPerFile(t *testing.T) {
+	products := loadTestOperations(t, testSchema, "products.graphql", `query Products { products { id } }`)
+	nodes := loadTestOperations(t, testSchema, "nodes.graphql", `query Node($id: ID!) { node(id: $id) { id } }`)
+
+	alone, err := NewGoGenerator("queries").Generate("nodes.graphql", nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := NewGoGenerator("queries")
+	first, err := generator.Generate("products.graphql", products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := generator.Generate("nodes.graphql", nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(second) != string(alone) {
+		t.Errorf("expected the names of a file not to depend on other files, got\n%s", second)
+	}
+	compile(t, first, second)
+
+	duplicate := loadTestOperations(t, testSchema, "more.graphql", `query Products { products { name } }`)
+	if _, err := generator.Generate("more.graphql", duplicate); err == nil ||
+		!strings.Contains(err.Error(), "ProductsData of more.graphql is also generated from products.graphql") {
+		t.Errorf("expected the collision to name both files, got %v", err)
+	}
+}
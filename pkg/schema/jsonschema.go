@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"encoding/json"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+)
+
+var (
+	jsonScalars = map[string]string{
+		"ID":      "string",
+		"String":  "string",
+		"Int":     "integer",
+		"Float":   "number",
+		"Boolean": "boolean",
+	}
+)
+
+// JSONSchema returns the JSON Schema of the data the operation returns, which templates receive as `data`
+func (op *Operation) JSONSchema() ([]byte, error) {
+	schema := objectSchema(op.schema, op.root(), []ast.SelectionSet{op.definition.SelectionSet})
+	schema["$schema"] = JSONSchemaDraft
+	schema["title"] = op.Name
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// objectSchema describes the fields selected on the parent, every field is present unless selected on another type
+func objectSchema(s *ast.Schema, parent *ast.Definition, sets []ast.SelectionSet) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, field := range selections(parent, sets) {
+		if field.definition == nil {
+			continue
+		}
+		properties[field.key] = typeSchema(s, field.definition.Type, field.sets)
+		if !field.optional {
+			required = append(required, field.key)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(s *ast.Schema, t *ast.Type, sets []ast.SelectionSet) map[string]interface{} {
+	var schema map[string]interface{}
+	if t.Elem != nil {
+		schema = map[string]interface{}{
+			"type":  "array",
+			"items": typeSchema(s, t.Elem, sets),
+		}
+	} else {
+		definition := s.Types[t.NamedType]
+		switch {
+		case isComposite(definition):
+			schema = objectSchema(s, definition, sets)
+		case definition != nil && definition.Kind == ast.Enum:
+			values := []interface{}{}
+			for _, value := range definition.EnumValues {
+				values = append(values, value.Name)
+			}
+			schema = map[string]interface{}{"type": "string", "enum": values}
+		case jsonScalars[t.NamedType] != "":
+			schema = map[string]interface{}{"type": jsonScalars[t.NamedType]}
+		default:
+			// custom scalars can hold any value
+			return map[string]interface{}{"description": t.NamedType}
+		}
+	}
+
+	if !t.NonNull {
+		schema["type"] = []interface{}{schema["type"], "null"}
+		if values, ok := schema["enum"].([]interface{}); ok {
+			schema["enum"] = append(values, nil)
+		}
+	}
+	return schema
+}
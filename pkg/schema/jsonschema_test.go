@@ -0,0 +1,85 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	operations := loadTestOperations(t, testSchema, "products.graphql", testQuery)
+
+	b, err := operations[1].JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := map[string]interface{}{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title": "Node",
+		"type": "object",
+		"additionalProperties": false,
+		"required": ["node"],
+		"properties": {
+			"node": {
+				"type": ["object", "null"],
+				"additionalProperties": false,
+				"required": ["__typename", "id"],
+				"properties": {
+					"__typename": {"type": "string"},
+					"id": {"type": "string"},
+					"name": {"type": "string"}
+				}
+			}
+		}
+	}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected schema\n%s", b)
+	}
+}
+
+func TestJSONSchemaTypes(t *testing.T) {
+	operations := loadTestOperations(t, testSchema, "products.graphql", testQuery)
+
+	b, err := operations[0].JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := struct {
+		Properties struct {
+			Products struct {
+				Type  string `json:"type"`
+				Items struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"items"`
+			} `json:"products"`
+		} `json:"properties"`
+	}{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"price":  `{"description":"Money"}`,
+		"status": `{"enum":["ACTIVE","HIDDEN",null],"type":["string","null"]}`,
+		"tags":   `{"items":{"type":"string"},"type":["array","null"]}`,
+	}
+	for key, property := range expected {
+		var compact interface{}
+		_ = json.Unmarshal(schema.Properties.Products.Items.Properties[key], &compact)
+		b, _ := json.Marshal(compact)
+		if string(b) != property {
+			t.Errorf("%s: expected %s, got %s", key, property, b)
+		}
+	}
+	if schema.Properties.Products.Type != "array" {
+		t.Errorf("expected a list of products, got %s", schema.Properties.Products.Type)
+	}
+}
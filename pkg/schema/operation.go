@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Operation is an operation of a query file, validated against the schema
+type Operation struct {
+	// Name is the name of the operation, or of the file for anonymous operations
+	Name       string
+	File       string
+	definition *ast.OperationDefinition
+	schema     *ast.Schema
+}
+
+// selection is a field of the response, merged from every selection of the same key
+type selection struct {
+	key        string
+	definition *ast.FieldDefinition
+	sets       []ast.SelectionSet
+	// optional is set for fields selected only within fragments on other types, which can be absent
+	optional bool
+}
+
+// LoadOperations reads the operations in the query file, or returns the problems when it does not validate
+func LoadOperations(s *ast.Schema, path string) ([]*Operation, []Problem, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	document, errs := gqlparser.LoadQuery(s, string(b))
+	if len(errs) > 0 {
+		problems := []Problem{}
+		for _, e := range errs {
+			problems = append(problems, newProblem(path, e))
+		}
+		return nil, problems, nil
+	}
+
+	operations := []*Operation{}
+	for _, definition := range document.Operations {
+		name := definition.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		operations = append(operations, &Operation{
+			Name:       name,
+			File:       path,
+			definition: definition,
+			schema:     s,
+		})
+	}
+	return operations, nil, nil
+}
+
+// root returns the type the operation selects its fields on
+func (op *Operation) root() *ast.Definition {
+	switch op.definition.Operation {
+	case ast.Mutation:
+		return op.schema.Mutation
+	case ast.Subscription:
+		return op.schema.Subscription
+	}
+	return op.schema.Query
+}
+
+// selections returns the fields selected on the parent type by the selection sets, in order of appearance
+func selections(parent *ast.Definition, sets []ast.SelectionSet) []*selection {
+	fields := []*selection{}
+	index := map[string]*selection{}
+	for _, set := range sets {
+		collect(set, parent, false, &fields, index)
+	}
+	return fields
+}
+
+func collect(set ast.SelectionSet, parent *ast.Definition, optional bool, fields *[]*selection, index map[string]*selection) {
+	for _, s := range set {
+		switch s := s.(type) {
+		case *ast.Field:
+			key := s.Alias
+			if key == "" {
+				key = s.Name
+			}
+			if existing, ok := index[key]; ok {
+				existing.sets = append(existing.sets, s.SelectionSet)
+				existing.optional = existing.optional && optional
+				continue
+			}
+			definition := s.Definition
+			if s.Name == "__typename" {
+				// the parser types __typename as nullable, while it never is
+				definition = &ast.FieldDefinition{Name: s.Name, Type: ast.NonNullNamedType("String", nil)}
+			}
+			field := &selection{key: key, definition: definition, sets: []ast.SelectionSet{s.SelectionSet}, optional: optional}
+			index[key] = field
+			*fields = append(*fields, field)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				collect(s.Definition.SelectionSet, parent, optional || s.Definition.TypeCondition != parent.Name, fields, index)
+			}
+		case *ast.InlineFragment:
+			collect(s.SelectionSet, parent, optional || (s.TypeCondition != "" && s.TypeCondition != parent.Name), fields, index)
+		}
+	}
+}
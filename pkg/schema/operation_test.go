@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOperations(t *testing.T) {
+	operations := loadTestOperations(t, testSchema, "products.graphql", testQuery)
+	if len(operations) != 2 || operations[0].Name != "Products" || operations[1].Name != "Node" {
+		t.Fatalf("expected the operations of the file, got %v", operations)
+	}
+
+	anonymous := loadTestOperations(t, testSchema, "product-list.graphql", `{ products { id } }`)
+	if len(anonymous) != 1 || anonymous[0].Name != "product-list" {
+		t.Errorf("expected an anonymous operation to be named after the file, got %v", anonymous)
+	}
+}
+
+func TestLoadOperationsProblems(t *testing.T) {
+	s, err := Parse(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "products.graphql")
+	if err := ioutil.WriteFile(path, []byte("query Products {\n  products { id\n    title }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	operations, problems, err := LoadOperations(s, path)
+	if err != nil || operations != nil {
+		t.Fatalf("expected only problems, got %v: %v", operations, err)
+	}
+	if len(problems) != 1 || problems[0].File != path || problems[0].Line != 3 || problems[0].Column != 5 {
+		t.Errorf("expected the unknown field on line 3, got %v", problems)
+	}
+}
@@ -0,0 +1,140 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrorSchemaNotPulled = errors.New("no schema found, pull it with `afosto gql schema pull`")
+)
+
+// Problem is an error found in a query file
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// Pull introspects the schema of the API and returns it in the schema definition language
+func Pull(ctx context.Context, ac *client.AfostoClient) (string, error) {
+	result, err := introspect(ctx, ac, IntrospectionQuery)
+	if isUnknownField(err, "isRepeatable") {
+		// servers implementing specs before 2021 do not know repeatable directives
+		result, err = introspect(ctx, ac, legacyIntrospectionQuery)
+	}
+	if err != nil {
+		return "", err
+	}
+	if result.Schema.QueryType == nil {
+		return "", errors.New("the API did not return its schema")
+	}
+
+	sdl := result.Schema.toSDL()
+	if _, err := Parse(sdl); err != nil {
+		return "", fmt.Errorf("could not read the schema of the API: %w", err)
+	}
+	return sdl, nil
+}
+
+func introspect(ctx context.Context, ac *client.AfostoClient, query string) (introspection, error) {
+	result := introspection{}
+	q, err := client.NewQuery(query, "", nil)
+	if err != nil {
+		return result, err
+	}
+	_, err = ac.QueryInto(ctx, q, &result)
+	return result, err
+}
+
+// isUnknownField reports whether the server rejected the query for asking a field it does not have, which it
+// either reports in the errors of the query or in the body of a 400 response
+func isUnknownField(err error, field string) bool {
+	return err != nil && strings.Contains(err.Error(), "Cannot query field") && strings.Contains(err.Error(), field)
+}
+
+// CachePath returns where the schema of the API at apiURL is kept, like ~/.cache/afosto/schema/afosto.app-api.graphql
+func CachePath(apiURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil {
+		return "", err
+	}
+	name := strings.Trim(u.Host+strings.ReplaceAll(u.Path, "/", "-"), "-")
+	name = strings.NewReplacer(":", "-", "\\", "-").Replace(name)
+	return filepath.Join(dir, "afosto", "schema", name+".graphql"), nil
+}
+
+// Save writes the schema to the path
+func Save(path string, sdl string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(sdl), 0600)
+}
+
+// Load reads the schema pulled to the path
+func Load(path string) (*ast.Schema, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrorSchemaNotPulled
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
+
+// Parse reads a schema in the schema definition language
+func Parse(sdl string) (*ast.Schema, error) {
+	s, err := gqlparser.LoadSchema(&ast.Source{Name: "schema.graphql", Input: sdl})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ValidateFile checks the queries in the file against the schema, returning the problems found
+func ValidateFile(s *ast.Schema, path string) ([]Problem, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	_, errs := gqlparser.LoadQuery(s, string(b))
+	problems := []Problem{}
+	for _, e := range errs {
+		problems = append(problems, newProblem(path, e))
+	}
+	return problems, nil
+}
+
+func newProblem(path string, e *gqlerror.Error) Problem {
+	problem := Problem{File: path, Message: e.Message}
+	if len(e.Locations) > 0 {
+		problem.Line = e.Locations[0].Line
+		problem.Column = e.Locations[0].Column
+	}
+	return problem
+}
+
+// String formats the problem like compilers do, as file:line:column: message
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
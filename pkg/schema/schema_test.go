@@ -0,0 +1,223 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/afosto/cli/pkg/client"
+	"github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// introspectionResponse is what the API answers to the introspection query, trimmed to a few types
+const introspectionResponse = `{"data": {"__schema": {
+	"queryType": {"name": "Query"},
+	"mutationType": {"name": "Mutation"},
+	"subscriptionType": null,
+	"directives": [
+		{"name": "cached", "description": "Caches the \"\"\"field\"\"\"", "isRepeatable": true, "locations": ["FIELD", "QUERY"], "args": [
+			{"name": "ttl", "type": {"kind": "SCALAR", "name": "Int"}, "defaultValue": "60"}
+		]},
+		{"name": "deprecated", "isRepeatable": false, "locations": ["FIELD_DEFINITION"], "args": []}
+	],
+	"types": [
+		{"kind": "OBJECT", "name": "Query", "fields": [
+			{"name": "product", "args": [{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}],
+				"type": {"kind": "INTERFACE", "name": "Node"}},
+			{"name": "products", "args": [{"name": "filter", "type": {"kind": "INPUT_OBJECT", "name": "ProductFilter"}}],
+				"type": {"kind": "NON_NULL", "ofType": {"kind": "LIST", "ofType": {"kind": "NON_NULL", "ofType": {"kind": "OBJECT", "name": "Product"}}}}},
+			{"name": "search", "args": [], "type": {"kind": "LIST", "ofType": {"kind": "UNION", "name": "SearchResult"}}}
+		], "interfaces": []},
+		{"kind": "OBJECT", "name": "Mutation", "fields": [
+			{"name": "archive", "args": [{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}],
+				"type": {"kind": "SCALAR", "name": "Boolean"}}
+		], "interfaces": []},
+		{"kind": "INTERFACE", "name": "Node", "fields": [
+			{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}
+		], "possibleTypes": [{"kind": "OBJECT", "name": "Product"}]},
+		{"kind": "OBJECT", "name": "Product", "description": "A product\nof the shop", "fields": [
+			{"name": "id", "args": [], "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}},
+			{"name": "price", "args": [], "type": {"kind": "SCALAR", "name": "Money"}},
+			{"name": "sku", "args": [], "type": {"kind": "SCALAR", "name": "String"}, "isDeprecated": true, "deprecationReason": "Use \"code\""},
+			{"name": "status", "args": [], "type": {"kind": "ENUM", "name": "Status"}}
+		], "interfaces": [{"kind": "INTERFACE", "name": "Node"}]},
+		{"kind": "OBJECT", "name": "Category", "fields": [
+			{"name": "name", "args": [], "type": {"kind": "SCALAR", "name": "String"}}
+		], "interfaces": []},
+		{"kind": "UNION", "name": "SearchResult", "possibleTypes": [{"kind": "OBJECT", "name": "Product"}, {"kind": "OBJECT", "name": "Category"}]},
+		{"kind": "ENUM", "name": "Status", "enumValues": [
+			{"name": "ACTIVE"},
+			{"name": "HIDDEN", "isDeprecated": true}
+		]},
+		{"kind": "INPUT_OBJECT", "name": "ProductFilter", "inputFields": [
+			{"name": "status", "type": {"kind": "ENUM", "name": "Status"}, "defaultValue": "ACTIVE"},
+			{"name": "first", "type": {"kind": "SCALAR", "name": "Int"}, "defaultValue": "10"}
+		]},
+		{"kind": "SCALAR", "name": "Money", "description": "An amount in cents"},
+		{"kind": "SCALAR", "name": "String"},
+		{"kind": "SCALAR", "name": "Int"},
+		{"kind": "OBJECT", "name": "__Type", "fields": []}
+	]
+}}}`
+
+// newIntrospectionServer answers queries with the response and returns a client for it
+func newIntrospectionServer(t *testing.T, handler http.HandlerFunc) *client.AfostoClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	return client.New("tenant", client.WithBaseURL(server.URL), client.WithToken("token"), client.WithLogger(logger))
+}
+
+func TestPull(t *testing.T) {
+	ac := newIntrospectionServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(introspectionResponse))
+	})
+
+	sdl, err := Pull(context.Background(), ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(sdl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Query.Name != "Query" || s.Mutation.Name != "Mutation" || s.Subscription != nil {
+		t.Errorf("unexpected root types in\n%s", sdl)
+	}
+	product := s.Types["Product"]
+	if product == nil || product.Description != "A product\nof the shop" || !reflect.DeepEqual(product.Interfaces, []string{"Node"}) {
+		t.Fatalf("expected the product type in\n%s", sdl)
+	}
+	if reason := product.Fields.ForName("sku").Directives.ForName("deprecated").Arguments.ForName("reason"); reason == nil || reason.Value.Raw != `Use "code"` {
+		t.Errorf("expected the reason of the deprecation in\n%s", sdl)
+	}
+	if products := s.Query.Fields.ForName("products"); products.Type.String() != "[Product!]!" || products.Arguments.ForName("filter") == nil {
+		t.Errorf("expected the types of the fields to be kept in\n%s", sdl)
+	}
+	if first := s.Types["ProductFilter"].Fields.ForName("first"); first.DefaultValue == nil || first.DefaultValue.Raw != "10" {
+		t.Errorf("expected the default values to be kept in\n%s", sdl)
+	}
+	if union := s.Types["SearchResult"]; union == nil || !reflect.DeepEqual(union.Types, []string{"Product", "Category"}) {
+		t.Errorf("expected the union in\n%s", sdl)
+	}
+	if values := s.Types["Status"].EnumValues; len(values) != 2 || values[1].Directives.ForName("deprecated") == nil {
+		t.Errorf("expected the enum values in\n%s", sdl)
+	}
+	if cached := s.Directives["cached"]; cached == nil || !cached.IsRepeatable || cached.Description != `Caches the """field"""` {
+		t.Errorf("expected the directive in\n%s", sdl)
+	}
+	if s.Types["__Type"].BuiltIn != true || s.Types["Money"].Description != "An amount in cents" {
+		t.Errorf("expected the builtin types to be left to the parser in\n%s", sdl)
+	}
+
+	// pulling the schema written out again gives the same schema
+	path := filepath.Join(t.TempDir(), "schema.graphql")
+	if err := Save(path, sdl); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil || len(loaded.Types) != len(s.Types) {
+		t.Errorf("expected the saved schema to load, got %v", err)
+	}
+}
+
+func TestPullWithoutRepeatableDirectives(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest} {
+		queries := []string{}
+		ac := newIntrospectionServer(t, func(w http.ResponseWriter, r *http.Request) {
+			q := client.Query{}
+			_ = json.NewDecoder(r.Body).Decode(&q)
+			queries = append(queries, q.Query)
+			if strings.Contains(q.Query, "isRepeatable") {
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(`{"errors": [{"message": "Cannot query field \"isRepeatable\" on type \"__Directive\".", "locations": [{"line": 10, "column": 7}]}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(introspectionResponse))
+		})
+
+		sdl, err := Pull(context.Background(), ac)
+		if err != nil {
+			t.Fatalf("%d: %v", status, err)
+		}
+		if len(queries) != 2 || strings.Contains(queries[1], "isRepeatable") || !strings.Contains(sdl, "type Product") {
+			t.Errorf("%d: expected the schema to be pulled again without isRepeatable, got %d queries", status, len(queries))
+		}
+	}
+}
+
+func TestPullFails(t *testing.T) {
+	requests := 0
+	ac := newIntrospectionServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"errors": [{"message": "Cannot query field \"__schema\" on type \"Query\"."}]}`))
+	})
+
+	if _, err := Pull(context.Background(), ac); err == nil || requests != 1 {
+		t.Errorf("expected the error without pulling again, got %v after %d requests", err, requests)
+	}
+}
+
+func TestLoadNotPulled(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "schema.graphql")); err != ErrorSchemaNotPulled {
+		t.Errorf("expected %v, got %v", ErrorSchemaNotPulled, err)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	ac := newIntrospectionServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(introspectionResponse))
+	})
+	sdl, err := Pull(context.Background(), ac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Parse(sdl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.graphql")
+	invalid := filepath.Join(dir, "invalid.graphql")
+	if err := os.WriteFile(valid, []byte("query Products {\n  products(filter: {first: 5}) { id price }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(invalid, []byte("# products\nquery Products {\n  products {\n    id\n      name\n  }\n}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if problems, err := ValidateFile(s, valid); err != nil || len(problems) != 0 {
+		t.Errorf("expected no problems, got %v: %v", problems, err)
+	}
+	problems, err := ValidateFile(s, invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Line != 5 || problems[0].Column != 7 {
+		t.Fatalf("expected the unknown field on line 5, got %v", problems)
+	}
+	if expected := invalid + `:5:7: Cannot query field "name" on type "Product".`; problems[0].String() != expected {
+		t.Errorf("expected %s, got %s", expected, problems[0])
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	path, err := CachePath("https://afosto.app/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "afosto.app-api.graphql" || filepath.Base(filepath.Dir(path)) != "schema" {
+		t.Errorf("unexpected path %s", path)
+	}
+}
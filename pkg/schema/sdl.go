@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"fmt"
+	"github.com/vektah/gqlparser/v2/parser"
+	"github.com/vektah/gqlparser/v2/validator"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// builtins holds the types and directives gqlparser defines itself, which the schema must not define again
+var builtins = func() map[string]bool {
+	names := map[string]bool{}
+	document, err := parser.ParseSchema(validator.Prelude)
+	if err != nil {
+		panic(err)
+	}
+	for _, definition := range document.Definitions {
+		names[definition.Name] = true
+	}
+	for _, directive := range document.Directives {
+		names["@"+directive.Name] = true
+	}
+	return names
+}()
+
+// toSDL writes the introspected schema in the schema definition language
+func (s introspectionSchema) toSDL() string {
+	sb := &strings.Builder{}
+
+	sb.WriteString("schema {\n")
+	for _, root := range []struct {
+		operation string
+		t         *namedType
+	}{{"query", s.QueryType}, {"mutation", s.MutationType}, {"subscription", s.SubscriptionType}} {
+		if root.t != nil {
+			fmt.Fprintf(sb, "  %s: %s\n", root.operation, root.t.Name)
+		}
+	}
+	sb.WriteString("}\n")
+
+	directives := append([]directiveDefinition{}, s.Directives...)
+	sort.Slice(directives, func(i, j int) bool { return directives[i].Name < directives[j].Name })
+	for _, d := range directives {
+		if builtins["@"+d.Name] {
+			continue
+		}
+		sb.WriteString("\n")
+		writeDescription(sb, d.Description, "")
+		fmt.Fprintf(sb, "directive @%s%s", d.Name, arguments(d.Args))
+		if d.IsRepeatable {
+			sb.WriteString(" repeatable")
+		}
+		fmt.Fprintf(sb, " on %s\n", strings.Join(d.Locations, " | "))
+	}
+
+	types := append([]fullType{}, s.Types...)
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	for _, t := range types {
+		if builtins[t.Name] || strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+		sb.WriteString("\n")
+		writeDescription(sb, t.Description, "")
+		t.writeSDL(sb)
+	}
+
+	return sb.String()
+}
+
+func (t fullType) writeSDL(sb *strings.Builder) {
+	switch t.Kind {
+	case "SCALAR":
+		fmt.Fprintf(sb, "scalar %s\n", t.Name)
+	case "OBJECT", "INTERFACE":
+		keyword := "type"
+		if t.Kind == "INTERFACE" {
+			keyword = "interface"
+		}
+		fmt.Fprintf(sb, "%s %s", keyword, t.Name)
+		if len(t.Interfaces) > 0 {
+			names := []string{}
+			for _, i := range t.Interfaces {
+				names = append(names, i.Name)
+			}
+			fmt.Fprintf(sb, " implements %s", strings.Join(names, " & "))
+		}
+		sb.WriteString(" {\n")
+		for _, f := range t.Fields {
+			writeDescription(sb, f.Description, "  ")
+			fmt.Fprintf(sb, "  %s%s: %s%s\n", f.Name, arguments(f.Args), f.Type.String(), deprecated(f.IsDeprecated, f.DeprecationReason))
+		}
+		sb.WriteString("}\n")
+	case "UNION":
+		names := []string{}
+		for _, p := range t.PossibleTypes {
+			names = append(names, p.Name)
+		}
+		fmt.Fprintf(sb, "union %s = %s\n", t.Name, strings.Join(names, " | "))
+	case "ENUM":
+		fmt.Fprintf(sb, "enum %s {\n", t.Name)
+		for _, v := range t.EnumValues {
+			writeDescription(sb, v.Description, "  ")
+			fmt.Fprintf(sb, "  %s%s\n", v.Name, deprecated(v.IsDeprecated, v.DeprecationReason))
+		}
+		sb.WriteString("}\n")
+	case "INPUT_OBJECT":
+		fmt.Fprintf(sb, "input %s {\n", t.Name)
+		for _, f := range t.InputFields {
+			writeDescription(sb, f.Description, "  ")
+			fmt.Fprintf(sb, "  %s\n", f.String())
+		}
+		sb.WriteString("}\n")
+	}
+}
+
+func (tr typeRef) String() string {
+	switch tr.Kind {
+	case "NON_NULL":
+		if tr.OfType != nil {
+			return tr.OfType.String() + "!"
+		}
+	case "LIST":
+		if tr.OfType != nil {
+			return "[" + tr.OfType.String() + "]"
+		}
+	}
+	return tr.Name
+}
+
+func (iv inputValue) String() string {
+	s := iv.Name + ": " + iv.Type.String()
+	if iv.DefaultValue != nil {
+		s += " = " + *iv.DefaultValue
+	}
+	return s
+}
+
+func arguments(args []inputValue) string {
+	if len(args) == 0 {
+		return ""
+	}
+	list := []string{}
+	for _, arg := range args {
+		list = append(list, arg.String())
+	}
+	return "(" + strings.Join(list, ", ") + ")"
+}
+
+func deprecated(isDeprecated bool, reason *string) string {
+	if !isDeprecated {
+		return ""
+	}
+	if reason == nil || *reason == "" {
+		return " @deprecated"
+	}
+	return " @deprecated(reason: " + strconv.Quote(*reason) + ")"
+}
+
+func writeDescription(sb *strings.Builder, description string, indent string) {
+	if description == "" {
+		return
+	}
+	// the quotes on their own lines keep a description ending in a quote from closing the block early
+	fmt.Fprintf(sb, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(strings.ReplaceAll(description, `"""`, `\"""`), "\n") {
+		fmt.Fprintf(sb, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(sb, "%s\"\"\"\n", indent)
+}